@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"strconv"
 	"strings"
 
 	"github.com/onurravli/goception/token"
@@ -11,6 +12,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	// Pos returns the line and column the node is reported at in errors
+	Pos() (line, column int)
 }
 
 // Statement represents a statement node in our AST
@@ -37,6 +40,13 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() (int, int) {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return 0, 0
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -49,17 +59,22 @@ func (p *Program) String() string {
 
 // VarStatement represents a var statement - e.g., var x = 5;
 type VarStatement struct {
-	Token token.Token // the 'var' token
-	Name  *Identifier
-	Type  *TypeAnnotation // Optional type annotation
-	Value Expression
+	Token    token.Token // the 'var' token
+	Name     *Identifier
+	Type     *TypeAnnotation // Optional type annotation
+	Value    Expression
+	Exported bool // whether the declaration is marked with 'export'
 }
 
 func (vs *VarStatement) statementNode()       {}
 func (vs *VarStatement) TokenLiteral() string { return vs.Token.Literal }
+func (vs *VarStatement) Pos() (int, int)      { return vs.Token.Line, vs.Token.Column }
 func (vs *VarStatement) String() string {
 	var out bytes.Buffer
 
+	if vs.Exported {
+		out.WriteString("export ")
+	}
 	out.WriteString(vs.TokenLiteral() + " ")
 	out.WriteString(vs.Name.String())
 
@@ -81,17 +96,22 @@ func (vs *VarStatement) String() string {
 
 // ConstStatement represents a const statement - e.g., const x = 5;
 type ConstStatement struct {
-	Token token.Token // the 'const' token
-	Name  *Identifier
-	Type  *TypeAnnotation // Optional type annotation
-	Value Expression
+	Token    token.Token // the 'const' token
+	Name     *Identifier
+	Type     *TypeAnnotation // Optional type annotation
+	Value    Expression
+	Exported bool // whether the declaration is marked with 'export'
 }
 
 func (cs *ConstStatement) statementNode()       {}
 func (cs *ConstStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ConstStatement) Pos() (int, int)      { return cs.Token.Line, cs.Token.Column }
 func (cs *ConstStatement) String() string {
 	var out bytes.Buffer
 
+	if cs.Exported {
+		out.WriteString("export ")
+	}
 	out.WriteString(cs.TokenLiteral() + " ")
 	out.WriteString(cs.Name.String())
 
@@ -119,6 +139,7 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() (int, int)      { return rs.Token.Line, rs.Token.Column }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -133,6 +154,81 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// WhileStatement represents a while loop - e.g., while (x < 10) { x = x + 1; }
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() (int, int)      { return ws.Token.Line, ws.Token.Column }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// ForStatement represents a C-style for loop - e.g., for (var i = 0; i < 10; i = i + 1) { ... }
+// Each of Init, Condition and Update may be nil when omitted.
+type ForStatement struct {
+	Token     token.Token // the 'for' token
+	Init      Statement
+	Condition Expression
+	Update    Expression
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() (int, int)      { return fs.Token.Line, fs.Token.Column }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Init != nil {
+		out.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+	}
+	out.WriteString("; ")
+	if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+	}
+	out.WriteString("; ")
+	if fs.Update != nil {
+		out.WriteString(fs.Update.String())
+	}
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// BreakStatement represents a break statement - e.g., break;
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() (int, int)      { return bs.Token.Line, bs.Token.Column }
+func (bs *BreakStatement) String() string       { return bs.TokenLiteral() + ";" }
+
+// ContinueStatement represents a continue statement - e.g., continue;
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() (int, int)      { return cs.Token.Line, cs.Token.Column }
+func (cs *ContinueStatement) String() string       { return cs.TokenLiteral() + ";" }
+
 // ExpressionStatement represents a statement that consists of just an expression
 type ExpressionStatement struct {
 	Token      token.Token // the first token of the expression
@@ -141,6 +237,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() (int, int)      { return es.Token.Line, es.Token.Column }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -156,6 +253,7 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() (int, int)      { return bs.Token.Line, bs.Token.Column }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -174,6 +272,7 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() (int, int)      { return i.Token.Line, i.Token.Column }
 func (i *Identifier) String() string       { return i.Value }
 
 // IntegerLiteral represents an integer - e.g., 5, 10, etc.
@@ -184,8 +283,20 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() (int, int)      { return il.Token.Line, il.Token.Column }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// FloatLiteral represents a floating-point number - e.g., 3.14, 1e-9, .5, etc.
+type FloatLiteral struct {
+	Token token.Token // the token.FLOAT token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() (int, int)      { return fl.Token.Line, fl.Token.Column }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 // StringLiteral represents a string - e.g., "hello", "world", etc.
 type StringLiteral struct {
 	Token token.Token // the token.STRING token
@@ -194,8 +305,20 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() (int, int)      { return sl.Token.Line, sl.Token.Column }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// CharLiteral represents a character - e.g., 'a', '\n', etc.
+type CharLiteral struct {
+	Token token.Token // the token.CHAR token
+	Value rune
+}
+
+func (cl *CharLiteral) expressionNode()      {}
+func (cl *CharLiteral) TokenLiteral() string { return cl.Token.Literal }
+func (cl *CharLiteral) Pos() (int, int)      { return cl.Token.Line, cl.Token.Column }
+func (cl *CharLiteral) String() string       { return strconv.QuoteRune(cl.Value) }
+
 // Boolean represents a boolean - e.g., true, false
 type Boolean struct {
 	Token token.Token // the token.TRUE or token.FALSE token
@@ -204,6 +327,7 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() (int, int)      { return b.Token.Line, b.Token.Column }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 // BooleanLiteral represents a boolean - e.g., true, false
@@ -214,8 +338,19 @@ type BooleanLiteral struct {
 
 func (b *BooleanLiteral) expressionNode()      {}
 func (b *BooleanLiteral) TokenLiteral() string { return b.Token.Literal }
+func (b *BooleanLiteral) Pos() (int, int)      { return b.Token.Line, b.Token.Column }
 func (b *BooleanLiteral) String() string       { return b.Token.Literal }
 
+// NullLiteral represents the null value - e.g., null
+type NullLiteral struct {
+	Token token.Token // the token.NULL token
+}
+
+func (n *NullLiteral) expressionNode()      {}
+func (n *NullLiteral) TokenLiteral() string { return n.Token.Literal }
+func (n *NullLiteral) Pos() (int, int)      { return n.Token.Line, n.Token.Column }
+func (n *NullLiteral) String() string       { return n.Token.Literal }
+
 // PrefixExpression represents a prefix expression - e.g., !5, -10, etc.
 type PrefixExpression struct {
 	Token    token.Token // The prefix token, e.g. !
@@ -225,6 +360,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() (int, int)      { return pe.Token.Line, pe.Token.Column }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -246,6 +382,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() (int, int)      { return ie.Token.Line, ie.Token.Column }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -268,6 +405,7 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() (int, int)      { return ie.Token.Line, ie.Token.Column }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -294,6 +432,7 @@ type FunctionLiteral struct {
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() (int, int)      { return fl.Token.Line, fl.Token.Column }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -347,6 +486,10 @@ type CallExpression struct {
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+
+// Pos reports a call at the called expression rather than at its '(' token
+func (ce *CallExpression) Pos() (int, int) { return ce.Function.Pos() }
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -363,6 +506,111 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// ArrayLiteral represents an array literal - e.g., [1, 2, 3]
+type ArrayLiteral struct {
+	Token    token.Token // the '[' token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() (int, int)      { return al.Token.Line, al.Token.Column }
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashLiteral represents a hash literal - e.g., {"one": 1, "two": 2}
+type HashLiteral struct {
+	Token token.Token // the '{' token
+	Pairs []*HashPair // in source order
+}
+
+// HashPair is a single key-value pair of a HashLiteral
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() (int, int)      { return hl.Token.Line, hl.Token.Column }
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// IndexExpression represents an index expression - e.g., name[0]
+type IndexExpression struct {
+	Token token.Token // The '[' token
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() (int, int)      { return ie.Token.Line, ie.Token.Column }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// MemberExpression represents access to a member of a module - e.g., m.square
+type MemberExpression struct {
+	Token    token.Token // the '.' token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) Pos() (int, int)      { return me.Token.Line, me.Token.Column }
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
+// Source returns an expression as a script writes it, without the parentheses
+// String adds to show grouping, for naming variables and elements in errors -
+// e.g., m["a"][0]
+func Source(expr Expression) string {
+	switch expr := expr.(type) {
+	case *StringLiteral:
+		return strconv.Quote(expr.Value)
+	case *IndexExpression:
+		return Source(expr.Left) + "[" + Source(expr.Index) + "]"
+	case *MemberExpression:
+		return Source(expr.Object) + "." + expr.Property.Value
+	}
+	return expr.String()
+}
+
 // AssignmentExpression represents an assignment expression - e.g., x = 5
 type AssignmentExpression struct {
 	Token token.Token // The '=' token
@@ -372,6 +620,7 @@ type AssignmentExpression struct {
 
 func (ae *AssignmentExpression) expressionNode()      {}
 func (ae *AssignmentExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignmentExpression) Pos() (int, int)      { return ae.Token.Line, ae.Token.Column }
 func (ae *AssignmentExpression) String() string {
 	var out bytes.Buffer
 
@@ -385,33 +634,132 @@ func (ae *AssignmentExpression) String() string {
 	return out.String()
 }
 
-// TypeAnnotation represents a type annotation - e.g., : int
+// IndexAssignmentExpression represents an assignment to an indexed element - e.g., a[0] = 5
+type IndexAssignmentExpression struct {
+	Token  token.Token // The '=' token
+	Target *IndexExpression
+	Value  Expression
+}
+
+func (ia *IndexAssignmentExpression) expressionNode()      {}
+func (ia *IndexAssignmentExpression) TokenLiteral() string { return ia.Token.Literal }
+func (ia *IndexAssignmentExpression) Pos() (int, int)      { return ia.Token.Line, ia.Token.Column }
+func (ia *IndexAssignmentExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ia.Target.String())
+	out.WriteString(" = ")
+
+	if ia.Value != nil {
+		out.WriteString(ia.Value.String())
+	}
+
+	return out.String()
+}
+
+// TypeAnnotation represents a type annotation - e.g., : int. Annotations form
+// a tree: an array or map type may describe the types of its contents - e.g.,
+// : map<string, array<int>>. A function type with a signature has the value
+// "function" and describes its parameters and result - e.g., : (int, int) -> int.
+// A union has the value "union" and lists the types it accepts - e.g.,
+// : int | string, or : int? for int | null
 type TypeAnnotation struct {
-	Token token.Token // the type token (TYPE_INT, TYPE_STRING, etc.)
-	Value string
+	Token   token.Token // the type token (TYPE_INT, TYPE_STRING, etc.), or '(' for a function type
+	Value   string
+	Args    []*TypeAnnotation // the element type of an array, or the key and value types of a map
+	Params  []*TypeAnnotation // the parameter types of a function type
+	Return  *TypeAnnotation   // the result type of a function type, nil for other types
+	Options []*TypeAnnotation // the types accepted by a union, nil for other types
 }
 
 func (ta *TypeAnnotation) expressionNode()      {}
 func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
-func (ta *TypeAnnotation) String() string       { return ta.Value }
+func (ta *TypeAnnotation) Pos() (int, int)      { return ta.Token.Line, ta.Token.Column }
+func (ta *TypeAnnotation) String() string {
+	if ta.IsUnion() {
+		options := []string{}
+		for _, option := range ta.Options {
+			if option.IsSignature() {
+				// Without parentheses, the union would be the result of the function
+				options = append(options, "("+option.String()+")")
+			} else {
+				options = append(options, option.String())
+			}
+		}
+
+		// A type or null is written as an optional type
+		if len(options) == 2 && options[1] == "null" {
+			return options[0] + "?"
+		}
+		return strings.Join(options, " | ")
+	}
+	if ta.Args != nil {
+		args := []string{}
+		for _, arg := range ta.Args {
+			args = append(args, arg.String())
+		}
+		return ta.Value + "<" + strings.Join(args, ", ") + ">"
+	}
+	if !ta.IsSignature() {
+		return ta.Value
+	}
+
+	params := []string{}
+	for _, param := range ta.Params {
+		params = append(params, param.String())
+	}
+	return "(" + strings.Join(params, ", ") + ") -> " + ta.Return.String()
+}
+
+// IsUnion reports whether the annotation accepts values of several types
+func (ta *TypeAnnotation) IsUnion() bool {
+	return ta.Options != nil
+}
+
+// IsSignature reports whether the annotation is a function type that describes
+// its parameters and result, rather than any function
+func (ta *TypeAnnotation) IsSignature() bool {
+	return ta.Return != nil
+}
 
-// ImportStatement represents an import statement - e.g., import "filename.gct";
+// ImportStatement represents an import statement. A plain import binds every
+// name defined by the file; an alias binds the file as a module and a name list
+// binds only the names listed - e.g.,
+//
+//	import "filename.gct";
+//	import "filename.gct" as m;
+//	import { square, PI } from "filename.gct";
 type ImportStatement struct {
 	Token token.Token // the 'import' token
 	Path  *StringLiteral
+	Alias *Identifier   // the module name after 'as', if any
+	Names []*Identifier // the names inside braces, if any
 }
 
 func (is *ImportStatement) statementNode()       {}
 func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) Pos() (int, int)      { return is.Token.Line, is.Token.Column }
 func (is *ImportStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(is.TokenLiteral() + " ")
 
+	if is.Names != nil {
+		names := []string{}
+		for _, name := range is.Names {
+			names = append(names, name.String())
+		}
+		out.WriteString("{ " + strings.Join(names, ", ") + " } from ")
+	}
+
 	if is.Path != nil {
 		out.WriteString(is.Path.String())
 	}
 
+	if is.Alias != nil {
+		out.WriteString(" as " + is.Alias.String())
+	}
+
 	out.WriteString(";")
 
 	return out.String()
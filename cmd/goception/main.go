@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/onurravli/goception"
+)
+
+// Exit codes reported when running a file
+const (
+	exitRuntimeError = 1
+	exitParseError   = 3
+	exitIOError      = 4
+	exitTypeError    = 5
+	exitUsageError   = 2 // also used by the flag package
+)
+
+func main() {
+	printResult := flag.Bool("print", false, "print the value of the last statement after running a file")
+	debug := flag.Bool("debug", false, "log how imports are resolved to stderr")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-print] [-debug] [file.gct]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s check file.gct...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var options []goception.Option
+	if *debug {
+		options = append(options, goception.WithDebugLogger(log.New(os.Stderr, "debug: ", 0)))
+	}
+
+	if flag.Arg(0) == "check" {
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(exitUsageError)
+		}
+		os.Exit(checkFiles(flag.Args()[1:]))
+	}
+
+	if flag.NArg() > 0 {
+		// If a file is provided, execute it
+		filename := flag.Arg(0)
+		os.Exit(executeFile(filename, *printResult, options))
+	} else {
+		// Otherwise, start the REPL
+		fmt.Println("Goception - A small and fast scripting language written in Go")
+		fmt.Println("Type in commands")
+		os.Exit(startRepl(os.Stdin, os.Stdout, options))
+	}
+}
+
+// executeFile runs a script and returns the process exit code: 0 on success,
+// the code passed to exit(), or one of the exit codes above on failure.
+// Errors are written to stderr.
+func executeFile(filename string, printResult bool, options []goception.Option) int {
+	input, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %s\n", err)
+		return exitIOError
+	}
+
+	interp := goception.New(options...)
+	evaluated, err := interp.Run(context.Background(), string(input), filename)
+	if err != nil {
+		return reportError(os.Stderr, err)
+	}
+
+	if printResult {
+		fmt.Println(evaluated.Inspect())
+	}
+	return 0
+}
+
+// checkFiles parses and type checks scripts without running them, and returns
+// the process exit code: 0 when all of them are valid, otherwise the code of
+// the last error. Errors are written to stderr.
+func checkFiles(filenames []string) int {
+	code := 0
+	for _, filename := range filenames {
+		input, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %s\n", err)
+			code = exitIOError
+			continue
+		}
+
+		if err := goception.Check(string(input), filename); err != nil {
+			code = reportError(os.Stderr, err)
+		}
+	}
+	return code
+}
+
+// startRepl reads and evaluates lines until the input ends or a line calls
+// exit(), and returns the exit code. Scripts print to out, and the REPL keeps
+// reading its lines from in, so input() is not available.
+func startRepl(in io.Reader, out io.Writer, options []goception.Option) int {
+	scanner := bufio.NewScanner(in)
+	options = append(options, goception.WithStdout(out), goception.WithStdin(strings.NewReader("")))
+	interp := goception.New(options...)
+
+	for {
+		fmt.Fprint(out, ">> ")
+		scanned := scanner.Scan()
+		if !scanned {
+			return 0
+		}
+
+		evaluated, err := interp.Run(context.Background(), scanner.Text(), "<repl>")
+		var exitErr *goception.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.Code
+		}
+		if err != nil {
+			reportError(out, err)
+			continue
+		}
+
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+	}
+}
+
+// reportError prints an error returned by the interpreter and returns the
+// matching exit code
+func reportError(out io.Writer, err error) int {
+	var parseErr *goception.ParseError
+	var typeErr *goception.TypeError
+	var runtimeErr *goception.RuntimeError
+	var exitErr *goception.ExitError
+
+	switch {
+	case errors.As(err, &parseErr):
+		sourceErrors := make([]sourceError, len(parseErr.Errors))
+		for i, err := range parseErr.Errors {
+			sourceErrors[i] = sourceError{err.Line, err.Column, err.Message}
+		}
+		printSourceErrors(out, "parse error", parseErr.Source, sourceErrors)
+		return exitParseError
+	case errors.As(err, &typeErr):
+		sourceErrors := make([]sourceError, len(typeErr.Errors))
+		for i, err := range typeErr.Errors {
+			sourceErrors[i] = sourceError{err.Line, err.Column, err.Message}
+		}
+		printSourceErrors(out, "type error", typeErr.Source, sourceErrors)
+		return exitTypeError
+	case errors.As(err, &runtimeErr):
+		fmt.Fprintln(out, runtimeErr.Trace())
+		return exitRuntimeError
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	default:
+		// Exceeded limits and other errors that stopped evaluation
+		fmt.Fprintf(out, "ERROR: %s\n", err)
+		return exitRuntimeError
+	}
+}
+
+// sourceError is a parse or type error found before a script runs
+type sourceError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// printSourceErrors prints each error followed by the offending source line
+// and a caret under the column where the error was found, e.g.
+//
+//	parse error at line 1, column 15: expected next token to be ), got ; instead
+//	    1 | print(add(1, 2;
+//	      |               ^
+func printSourceErrors(out io.Writer, kind, source string, errors []sourceError) {
+	lines := strings.Split(source, "\n")
+
+	for _, err := range errors {
+		fmt.Fprintf(out, "%s at line %d, column %d: %s\n", kind, err.Line, err.Column, err.Message)
+
+		if err.Line < 1 || err.Line > len(lines) {
+			continue
+		}
+
+		sourceLine := strings.TrimRight(lines[err.Line-1], "\r")
+		gutter := fmt.Sprintf("%5d | ", err.Line)
+		fmt.Fprintf(out, "%s%s\n", gutter, sourceLine)
+
+		// Pad up to the column, reusing tabs from the source line so the caret lines up
+		var padding strings.Builder
+		for i, r := range []rune(sourceLine) {
+			if i >= err.Column-1 {
+				break
+			}
+			if r == '\t' {
+				padding.WriteRune('\t')
+			} else {
+				padding.WriteRune(' ')
+			}
+		}
+		for i := len([]rune(sourceLine)); i < err.Column-1; i++ {
+			padding.WriteRune(' ')
+		}
+
+		fmt.Fprintf(out, "%s| %s^\n", strings.Repeat(" ", len(gutter)-2), padding.String())
+	}
+}
@@ -0,0 +1,424 @@
+package goception
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/onurravli/goception/ast"
+	"github.com/onurravli/goception/evaluator"
+	"github.com/onurravli/goception/object"
+)
+
+var (
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+)
+
+// toObject converts a Go value to a script value
+func (i *Interpreter) toObject(value interface{}) (object.Object, error) {
+	if obj, ok := value.(object.Object); ok {
+		return obj, nil
+	}
+	if value == nil {
+		return evaluator.NULL, nil
+	}
+	return i.valueToObject(reflect.ValueOf(value))
+}
+
+// valueToObject converts a reflected Go value to a script value. Integers of
+// any size become INTEGER, slices and arrays become ARRAY, maps become HASH with
+// their pairs ordered by key, and functions become builtins.
+func (i *Interpreter) valueToObject(v reflect.Value) (object.Object, error) {
+	if v.IsValid() && v.Type().Implements(objectType) && !isNil(v) {
+		return v.Interface().(object.Object), nil
+	}
+	if v.IsValid() && v.Type() == functionType && !isNil(v) {
+		return v.Interface().(*Function).fn, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return evaluator.NULL, nil
+	case reflect.Bool:
+		if v.Bool() {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > 1<<63-1 {
+			return nil, fmt.Errorf("%d overflows INTEGER", v.Uint())
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		elements := make([]object.Object, v.Len())
+		for idx := range elements {
+			element, err := i.valueToObject(v.Index(idx))
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", idx, err)
+			}
+			elements[idx] = element
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return i.mapToHash(v)
+	case reflect.Func:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return i.wrapFunction("", v)
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return i.valueToObject(v.Elem())
+	default:
+		return nil, fmt.Errorf("cannot convert %s to a script value", v.Type())
+	}
+}
+
+// isNil reports whether v is a nil pointer, interface, map, slice, function or
+// channel. Values of other kinds cannot be nil.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// mapToHash converts a Go map to a hash, inserting the pairs in key order so
+// that printing the hash is deterministic
+func (i *Interpreter) mapToHash(v reflect.Value) (object.Object, error) {
+	type pair struct {
+		key   object.Hashable
+		value object.Object
+	}
+
+	pairs := make([]pair, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := i.valueToObject(iter.Key())
+		if err != nil {
+			return nil, fmt.Errorf("key %v: %w", iter.Key(), err)
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+		}
+		value, err := i.valueToObject(iter.Value())
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", key.Inspect(), err)
+		}
+		pairs = append(pairs, pair{hashKey, value})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].key.(object.Object).Inspect() < pairs[j].key.(object.Object).Inspect()
+	})
+
+	hash := object.NewHash()
+	for _, p := range pairs {
+		hash.Set(p.key, p.value)
+	}
+	return hash, nil
+}
+
+// fromObject converts a script value to a Go value of type t. A char is
+// accepted for a rune (int32) and an integer for a float; a value of
+// interface type receives the natural Go form of the script value.
+func (i *Interpreter) fromObject(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Interface {
+		switch {
+		case t.NumMethod() != 0 && reflect.TypeOf(obj).Implements(t):
+			return reflect.ValueOf(obj), nil
+		case t.NumMethod() != 0:
+			return reflect.Value{}, mismatch(obj, t)
+		case obj == evaluator.NULL:
+			return reflect.Zero(t), nil
+		default:
+			return i.fromObject(obj, naturalType(obj))
+		}
+	}
+
+	if t == functionType {
+		switch obj.(type) {
+		case *object.Function, *object.Builtin:
+			return reflect.ValueOf(&Function{interp: i, fn: obj}), nil
+		}
+	}
+
+	switch obj := obj.(type) {
+	case *object.Null:
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+	case *object.Boolean:
+		if t.Kind() == reflect.Bool {
+			return reflect.ValueOf(obj.Value).Convert(t), nil
+		}
+	case *object.Integer:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v := reflect.New(t).Elem()
+			if v.OverflowInt(obj.Value) {
+				return reflect.Value{}, fmt.Errorf("must fit in %s, got %d", t, obj.Value)
+			}
+			v.SetInt(obj.Value)
+			return v, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			v := reflect.New(t).Elem()
+			if obj.Value < 0 || v.OverflowUint(uint64(obj.Value)) {
+				return reflect.Value{}, fmt.Errorf("must fit in %s, got %d", t, obj.Value)
+			}
+			v.SetUint(uint64(obj.Value))
+			return v, nil
+		case reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(float64(obj.Value)).Convert(t), nil
+		}
+	case *object.Float:
+		switch t.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(obj.Value).Convert(t), nil
+		}
+	case *object.Char:
+		if t.Kind() == reflect.Int32 {
+			return reflect.ValueOf(obj.Value).Convert(t), nil
+		}
+	case *object.String:
+		if t.Kind() == reflect.String {
+			return reflect.ValueOf(obj.Value).Convert(t), nil
+		}
+	case *object.Array:
+		if t.Kind() == reflect.Slice {
+			v := reflect.MakeSlice(t, len(obj.Elements), len(obj.Elements))
+			for idx, element := range obj.Elements {
+				converted, err := i.fromObject(element, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("at index %d %w", idx, err)
+				}
+				v.Index(idx).Set(converted)
+			}
+			return v, nil
+		}
+	case *object.Hash:
+		if t.Kind() == reflect.Map {
+			v := reflect.MakeMapWithSize(t, obj.Len())
+			for _, pair := range obj.Ordered() {
+				key, err := i.fromObject(pair.Key, t.Key())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("at key %s %w", pair.Key.Inspect(), err)
+				}
+				value, err := i.fromObject(pair.Value, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("at key %s %w", pair.Key.Inspect(), err)
+				}
+				v.SetMapIndex(key, value)
+			}
+			return v, nil
+		}
+	}
+
+	return reflect.Value{}, mismatch(obj, t)
+}
+
+// naturalType returns the Go type a script value converts to when the target
+// is an empty interface
+func naturalType(obj object.Object) reflect.Type {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return reflect.TypeOf(false)
+	case *object.Integer:
+		return reflect.TypeOf(int64(0))
+	case *object.Float:
+		return reflect.TypeOf(float64(0))
+	case *object.Char:
+		return reflect.TypeOf(rune(0))
+	case *object.String:
+		return reflect.TypeOf("")
+	case *object.Array:
+		return reflect.TypeOf([]interface{}{})
+	case *object.Hash:
+		for _, pair := range obj.Ordered() {
+			if pair.Key.Type() != object.STRING_OBJ {
+				return reflect.TypeOf(map[interface{}]interface{}{})
+			}
+		}
+		return reflect.TypeOf(map[string]interface{}{})
+	default:
+		// Null and values without a Go form, such as functions, stay script values
+		return objectType
+	}
+}
+
+// mismatch reports a script value that cannot be converted to a Go type
+func mismatch(obj object.Object, t reflect.Type) error {
+	return fmt.Errorf("must be %s, got %s", scriptTypeName(t), obj.Type())
+}
+
+// scriptTypeName names the script type that converts to a Go type
+func scriptTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return object.BOOLEAN_OBJ
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return object.INTEGER_OBJ
+	case reflect.Float32, reflect.Float64:
+		return object.FLOAT_OBJ
+	case reflect.String:
+		return object.STRING_OBJ
+	case reflect.Slice:
+		return object.ARRAY_OBJ
+	case reflect.Map:
+		return object.HASH_OBJ
+	case reflect.Ptr:
+		if t == functionType {
+			return object.FUNCTION_OBJ
+		}
+		return t.String()
+	default:
+		return t.String()
+	}
+}
+
+// wrapFunction turns a Go function into a builtin. Arguments are converted to
+// the function's parameter types and checked against its arity. The function
+// may return nothing, a value, an error, or a value and an error; a non-nil
+// error or a panic becomes a script error. The name, if any, is used in
+// error messages.
+func (i *Interpreter) wrapFunction(name string, fn reflect.Value) (*object.Builtin, error) {
+	t := fn.Type()
+
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	results := t.NumOut()
+	if returnsError {
+		results--
+	}
+	if results > 1 {
+		return nil, fmt.Errorf("cannot register %s: it must return at most one value and an error", t)
+	}
+
+	params := t.NumIn()
+	variadic := t.IsVariadic()
+
+	subject, function := "", "Go function"
+	if name != "" {
+		subject = fmt.Sprintf(" to `%s`", name)
+		function = fmt.Sprintf("Go function `%s`", name)
+	}
+
+	// The signature lets the function be passed where a function type is expected
+	var paramTypes []*ast.TypeAnnotation
+	var returnType *ast.TypeAnnotation
+	if !variadic {
+		paramTypes = make([]*ast.TypeAnnotation, params)
+		for idx := range paramTypes {
+			paramTypes[idx] = annotationFor(t.In(idx))
+		}
+		if results == 1 {
+			returnType = annotationFor(t.Out(0))
+		}
+	}
+
+	return &object.Builtin{
+		ParamTypes: paramTypes,
+		ReturnType: returnType,
+		Fn: func(args ...object.Object) (result object.Object) {
+			if variadic && len(args) < params-1 {
+				return &object.Error{Message: fmt.Sprintf(
+					"wrong number of arguments. got=%d, want at least %d", len(args), params-1)}
+			}
+			if !variadic && len(args) != params {
+				return &object.Error{Message: fmt.Sprintf(
+					"wrong number of arguments. got=%d, want=%d", len(args), params)}
+			}
+
+			in := make([]reflect.Value, len(args))
+			for idx, arg := range args {
+				paramType := t.In(min(idx, params-1))
+				if variadic && idx >= params-1 {
+					paramType = paramType.Elem()
+				}
+				v, err := i.fromObject(arg, paramType)
+				if err != nil {
+					return &object.Error{Message: fmt.Sprintf("argument %d%s %s", idx, subject, err)}
+				}
+				in[idx] = v
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					result = &object.Error{Message: fmt.Sprintf("panic in %s: %v", function, r)}
+				}
+			}()
+
+			out := fn.Call(in)
+
+			if returnsError {
+				if err, _ := out[len(out)-1].Interface().(error); err != nil {
+					return &object.Error{Message: err.Error()}
+				}
+			}
+			if results == 0 {
+				return evaluator.NULL
+			}
+
+			obj, err := i.valueToObject(out[0])
+			if err != nil {
+				return &object.Error{Message: fmt.Sprintf("result of %s: %s", function, err)}
+			}
+			return obj
+		},
+	}, nil
+}
+
+// annotationFor returns the annotation of the script type a Go type converts
+// to and from, or nil if values of several script types convert to it
+func annotationFor(t reflect.Type) *ast.TypeAnnotation {
+	var name string
+	switch t.Kind() {
+	case reflect.Bool:
+		name = "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		name = "int"
+	case reflect.Float32, reflect.Float64:
+		name = "float"
+	case reflect.String:
+		name = "string"
+	case reflect.Slice:
+		if element := annotationFor(t.Elem()); element != nil {
+			return &ast.TypeAnnotation{Value: "array", Args: []*ast.TypeAnnotation{element}}
+		}
+		name = "array"
+	case reflect.Map:
+		key, value := annotationFor(t.Key()), annotationFor(t.Elem())
+		if key != nil && value != nil {
+			return &ast.TypeAnnotation{Value: "map", Args: []*ast.TypeAnnotation{key, value}}
+		}
+		name = "map"
+	case reflect.Ptr:
+		if t != functionType {
+			return nil
+		}
+		name = "function"
+	default:
+		// Runes accept both chars and integers, and interfaces accept anything
+		return nil
+	}
+	return &ast.TypeAnnotation{Value: name}
+}
@@ -0,0 +1,75 @@
+package goception
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onurravli/goception/evaluator"
+	"github.com/onurravli/goception/object"
+	"github.com/onurravli/goception/parser"
+	"github.com/onurravli/goception/typecheck"
+)
+
+// ParseError reports the syntax errors that stopped a script from running
+type ParseError struct {
+	Filename string
+	Source   string
+	Errors   []*parser.ParseError
+}
+
+func (e *ParseError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = fmt.Sprintf("%s: %s", e.Filename, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// TypeError reports the type errors that stopped a script from running
+type TypeError struct {
+	Filename string
+	Source   string
+	Errors   []*typecheck.Error
+}
+
+func (e *TypeError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = fmt.Sprintf("%s: %s", e.Filename, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// RuntimeError reports an error raised while evaluating a script. Err holds
+// the position of the error and the function calls it unwound through.
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	if e.Err.Line == 0 {
+		return e.Err.Message
+	}
+	if e.Err.File == "" {
+		return fmt.Sprintf("line %d, column %d: %s", e.Err.Line, e.Err.Column, e.Err.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Err.File, e.Err.Line, e.Err.Column, e.Err.Message)
+}
+
+// Trace returns the error message followed by its stack trace
+func (e *RuntimeError) Trace() string {
+	return e.Err.Trace()
+}
+
+// LimitError reports that a script exceeded a limit set with WithMaxCallDepth,
+// WithMaxSteps or WithMaxAllocation
+type LimitError = evaluator.LimitError
+
+// ExitError reports that a script called the exit builtin
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
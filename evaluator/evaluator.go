@@ -1,104 +1,356 @@
 package evaluator
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"log"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/onurravli/goception/ast"
 	"github.com/onurravli/goception/lexer"
 	"github.com/onurravli/goception/object"
 	"github.com/onurravli/goception/parser"
+	"github.com/onurravli/goception/typecheck"
 )
 
+// The singleton objects below and the builtins are shared by all evaluators.
+// They are never modified, so evaluators can run in separate goroutines.
 var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
 
-	// Track imported files to prevent circular imports
-	importedFiles = make(map[string]bool)
-
-	// Cache of imported file contents to avoid reading the same file multiple times
-	importCache = make(map[string]string)
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
-// Eval evaluates the given node and returns an object
-func Eval(node ast.Node, env *object.Environment) object.Object {
+// DefaultMaxCallDepth is the call depth limit used when Limits.MaxCallDepth
+// is zero. It stops runaway recursion well before the Go stack overflows.
+const DefaultMaxCallDepth = 10000
+
+// Limits bounds the resources a single evaluation may use. A zero field means
+// no limit, except for MaxCallDepth which then defaults to DefaultMaxCallDepth.
+type Limits struct {
+	MaxCallDepth  int   // maximum number of nested function calls
+	MaxSteps      int64 // maximum number of AST nodes evaluated
+	MaxAllocation int   // maximum length of a string in bytes, or of an array or hash
+}
+
+// LimitError reports that an evaluation exceeded one of its Limits
+type LimitError struct {
+	Limit string // the exceeded limit: "call depth", "step" or "allocation"
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded", e.Limit, e.Max)
+}
+
+// Evaluator evaluates programs and holds the state that outlives a single
+// evaluation, such as the files imported so far. An Evaluator must not be used
+// by more than one goroutine at a time, but separate evaluators are
+// independent and can run concurrently.
+type Evaluator struct {
+	// Limits bounds each evaluation started by Run or Call
+	Limits Limits
+
+	// Stdout and Stderr receive the output of print and eprint, and input
+	// reads lines from Stdin
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  io.Reader
+
+	// Debug, if not nil, logs diagnostics such as how imports are resolved
+	Debug *log.Logger
+
+	// builtins bound to this evaluator, because they use its streams
+	builtins map[string]*object.Builtin
+
+	// stdin buffers Stdin for input, and stdinSource is the reader it wraps
+	stdin       *bufio.Reader
+	stdinSource io.Reader
+
+	// Resolver finds the files scripts import
+	Resolver ImportResolver
+
+	// modules holds the environment of each file imported so far, keyed by the
+	// name its resolver gave it, so every file is evaluated once
+	modules map[string]*object.Environment
+
+	// importing is the chain of files whose imports are being evaluated,
+	// starting with the file that was run
+	importing []string
+
+	ctx    context.Context // stops the current evaluation when done
+	active int             // nesting of Run and Call, which is above 1 when Go code called back into a script
+	steps  int64           // nodes evaluated by the current evaluation
+	depth  int             // function calls in progress
+}
+
+// New creates an evaluator with no imported modules, which reads from and writes
+// to the standard streams of the process and resolves imports on the file
+// system, using GOCEPTION_PATH as the search path
+func New() *Evaluator {
+	e := &Evaluator{
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Stdin:    os.Stdin,
+		Resolver: NewSearchPathResolver(),
+		modules:  make(map[string]*object.Environment),
+	}
+
+	e.builtins = map[string]*object.Builtin{
+		"print":  {Fn: e.print},
+		"eprint": {Fn: e.eprint},
+		"input":  {Fn: e.input},
+	}
+
+	return e
+}
+
+// debugf logs a diagnostic message to the debug logger, if there is one
+func (e *Evaluator) debugf(format string, a ...interface{}) {
+	if e.Debug != nil {
+		e.Debug.Printf(format, a...)
+	}
+}
+
+// Run evaluates node as a new evaluation, which stops when ctx is done or a
+// limit is exceeded
+func (e *Evaluator) Run(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	defer e.begin(ctx)()
+	return e.Eval(node, env)
+}
+
+// Call calls a function or builtin as by ApplyFunction, as a new evaluation
+// which stops when ctx is done or a limit is exceeded
+func (e *Evaluator) Call(ctx context.Context, function object.Object, args []object.Object) object.Object {
+	defer e.begin(ctx)()
+	return e.ApplyFunction(function, args)
+}
+
+// begin starts an evaluation bounded by ctx and resets the step budget. When
+// an evaluation is already in progress, because a Go function called by the
+// script calls back into it, the outer context and budget stay in force.
+// It returns a function that ends the evaluation.
+func (e *Evaluator) begin(ctx context.Context) func() {
+	e.active++
+	if e.active == 1 {
+		e.ctx = ctx
+		e.steps = 0
+	}
+
+	return func() {
+		e.active--
+		if e.active == 0 {
+			e.ctx = nil
+		}
+	}
+}
+
+// Eval evaluates the given node and returns an object. An error raised while
+// evaluating the node is located at the innermost node it came from.
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	if abort := e.step(); abort != nil {
+		return abort
+	}
+
+	result := e.evalNode(node, env)
+
+	if err, ok := result.(*object.Error); ok && err.Line == 0 {
+		err.File = env.File()
+		err.Line, err.Column = node.Pos()
+	}
+
+	if abort := e.checkAllocation(result); abort != nil {
+		return abort
+	}
+
+	return result
+}
+
+// step counts an evaluated node against the step budget and, every so often,
+// checks whether the context is done. It returns an abort if evaluation must stop.
+func (e *Evaluator) step() *object.Abort {
+	e.steps++
+
+	if e.Limits.MaxSteps > 0 && e.steps > e.Limits.MaxSteps {
+		return &object.Abort{Err: &LimitError{Limit: "step", Max: e.Limits.MaxSteps}}
+	}
+
+	// Polling the context on every node would be needlessly slow
+	if e.ctx != nil && e.steps%256 == 0 {
+		if err := e.ctx.Err(); err != nil {
+			return &object.Abort{Err: err}
+		}
+	}
+
+	return nil
+}
+
+// checkAllocation returns an abort if obj is larger than the allocation limit
+func (e *Evaluator) checkAllocation(obj object.Object) *object.Abort {
+	if e.Limits.MaxAllocation <= 0 {
+		return nil
+	}
+
+	var size int
+	switch obj := obj.(type) {
+	case *object.String:
+		size = len(obj.Value)
+	case *object.Array:
+		size = len(obj.Elements)
+	case *object.Hash:
+		size = obj.Len()
+	}
+
+	if size > e.Limits.MaxAllocation {
+		return &object.Abort{Err: &LimitError{Limit: "allocation", Max: int64(e.Limits.MaxAllocation)}}
+	}
+	return nil
+}
+
+// evalNode evaluates a single node of the AST
+func (e *Evaluator) evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.Eval(node.Expression, env)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 	case *ast.VarStatement:
-		val := Eval(node.Value, env)
+		val := e.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 
 		// Type checking if a type annotation is provided
 		if node.Type != nil {
-			if !checkType(val, node.Type.Value) {
-				return newError("type mismatch: expected %s, got %s", node.Type.Value, val.Type())
+			if !checkType(val, node.Type) {
+				return newError("type mismatch: expected %s, got %s%s", node.Type, val.Type(), elementMismatch(val, node.Type))
 			}
 		}
 
+		// Values later assigned to the variable must have its annotated type, or
+		// the type of its initial value or null when it has no annotation
+		declared := inferredType(val)
+		if node.Type != nil {
+			declared = node.Type
+		}
+
+		nameFunction(val, node.Name.Value)
 		env.Set(node.Name.Value, val)
+		env.SetType(node.Name.Value, declared)
+		if node.Exported {
+			env.Export(node.Name.Value)
+		}
 	case *ast.ConstStatement:
-		val := Eval(node.Value, env)
+		val := e.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 
 		// Type checking if a type annotation is provided
 		if node.Type != nil {
-			if !checkType(val, node.Type.Value) {
-				return newError("type mismatch: expected %s, got %s", node.Type.Value, val.Type())
+			if !checkType(val, node.Type) {
+				return newError("type mismatch: expected %s, got %s%s", node.Type, val.Type(), elementMismatch(val, node.Type))
 			}
 		}
 
+		nameFunction(val, node.Name.Value)
 		env.SetConst(node.Name.Value, val)
+		if node.Exported {
+			env.Export(node.Name.Value)
+		}
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := e.Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.ImportStatement:
-		return evalImportStatement(node, env)
+		return e.evalImportStatement(node, env)
+	case *ast.WhileStatement:
+		return e.evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.CharLiteral:
+		return &object.Char{Value: node.Value}
 	case *ast.BooleanLiteral:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return e.evalLogicalExpression(node, env)
+		}
+
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalInfixExpression(node.Operator, left, right)
+	case *ast.ArrayLiteral:
+		elements := e.evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return e.evalHashLiteral(node, env)
+	case *ast.IndexExpression:
+		left := e.Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := e.Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		obj := e.Eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Property.Value)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return e.evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
@@ -111,66 +363,80 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			ReturnType: returnType,
 		}
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := e.Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
+		args := e.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
 
-		// Check parameter types if function has type annotations
-		if fn, ok := function.(*object.Function); ok && len(fn.ParamTypes) > 0 {
-			for i, paramType := range fn.ParamTypes {
-				if paramType != "" && i < len(args) {
-					if !checkType(args[i], paramType) {
-						return newError("type mismatch for argument %d: expected %s, got %s",
-							i, paramType, args[i].Type())
+		// Elements pushed to a variable declared with an element type must have it
+		if function == builtins["push"] && len(args) > 1 {
+			if elements := declaredElements(node.Arguments[0], args[0], env); elements != nil {
+				for i, arg := range args[1:] {
+					if !checkType(arg, elements[0]) {
+						return newError("type mismatch for argument %d: expected %s, got %s%s",
+							i+1, elements[0], arg.Type(), elementMismatch(arg, elements[0]))
 					}
 				}
 			}
 		}
 
-		result := applyFunction(function, args)
-
-		// Check return type if function has return type annotation
-		if fn, ok := function.(*object.Function); ok && fn.ReturnType != nil {
-			if !checkType(result, fn.ReturnType.Value) {
-				return newError("return type mismatch: expected %s, got %s",
-					fn.ReturnType.Value, result.Type())
+		result := e.ApplyFunction(function, args)
+
+		// An error that is still unlocated was raised by the call itself rather
+		// than inside the function body, so it is reported at the call site alone
+		if err, ok := result.(*object.Error); ok && err.Line != 0 {
+			if fn, ok := function.(*object.Function); ok {
+				line, column := node.Pos()
+				err.Stack = append(err.Stack, object.Frame{
+					Function: fn.Name,
+					File:     env.File(),
+					Line:     line,
+					Column:   column,
+				})
 			}
 		}
 
 		return result
 	case *ast.AssignmentExpression:
-		val := Eval(node.Value, env)
+		val := e.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 
+		declared := env.TypeOf(node.Name.Value)
+		if declared != nil && !checkType(val, declared) {
+			return newError("type mismatch in assignment to %s: expected %s, got %s%s",
+				node.Name.Value, declared, val.Type(), elementMismatch(val, declared))
+		}
+
 		if !env.Reassign(node.Name.Value, val) {
 			return newError("assignment to constant variable: %s", node.Name.Value)
 		}
 
 		return val
+	case *ast.IndexAssignmentExpression:
+		return e.evalIndexAssignmentExpression(node, env)
 	}
 
 	return NULL
 }
 
 // evalProgram evaluates a program
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
 			return result.Value
-		case *object.Error:
+		case *object.Error, *object.Exit, *object.Abort:
 			return result
 		}
 	}
@@ -178,16 +444,17 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
-// evalBlockStatement evaluates a block statement
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
-	var result object.Object
+// evalBlockStatement evaluates a block statement. An empty block evaluates to NULL.
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object = NULL
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.BREAK_OBJ ||
+				rt == object.CONTINUE_OBJ || isError(result) {
 				return result
 			}
 		}
@@ -196,6 +463,80 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// evalWhileStatement evaluates a while loop
+func (e *Evaluator) evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := e.Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := e.Eval(ws.Body, env)
+		if stop, value := loopControl(result); stop {
+			return value
+		}
+	}
+}
+
+// evalForStatement evaluates a C-style for loop. The initializer runs in its own
+// scope, so a loop variable declared there is not visible after the loop.
+func (e *Evaluator) evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+
+	if fs.Init != nil {
+		init := e.Eval(fs.Init, loopEnv)
+		if isError(init) {
+			return init
+		}
+	}
+
+	for {
+		if fs.Condition != nil {
+			condition := e.Eval(fs.Condition, loopEnv)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return NULL
+			}
+		}
+
+		result := e.Eval(fs.Body, loopEnv)
+		if stop, value := loopControl(result); stop {
+			return value
+		}
+
+		if fs.Update != nil {
+			update := e.Eval(fs.Update, loopEnv)
+			if isError(update) {
+				return update
+			}
+		}
+	}
+}
+
+// loopControl inspects the result of a loop body. It reports whether the loop
+// must stop and, if so, the value the loop statement evaluates to: the return
+// value or error itself so it keeps unwinding, or NULL after a break.
+func loopControl(result object.Object) (bool, object.Object) {
+	if result == nil {
+		return false, nil
+	}
+
+	switch result.Type() {
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.EXIT_OBJ, object.ABORT_OBJ:
+		return true, result
+	case object.BREAK_OBJ:
+		return true, NULL
+	default:
+		// Continue and ordinary values move on to the next iteration
+		return false, nil
+	}
+}
+
 // evalPrefixExpression evaluates a prefix expression
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
@@ -224,12 +565,14 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 
 // evalMinusPrefixOperatorExpression evaluates a minus prefix operator
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 // evalInfixExpression evaluates an infix expression
@@ -237,12 +580,16 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumeric(left) && isNumeric(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.CHAR_OBJ && right.Type() == object.CHAR_OBJ:
+		return evalCharInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && operator == "+":
 		return evalStringConcatenation(left, right)
 	case right.Type() == object.STRING_OBJ && operator == "+":
-		return evalStringConcatenation(right, left)
+		return evalStringConcatenation(left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
@@ -256,21 +603,48 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
-// evalStringConcatenation evaluates string concatenation with any type
-func evalStringConcatenation(str object.Object, other object.Object) object.Object {
-	stringVal := str.(*object.String).Value
+// evalLogicalExpression evaluates && and || with short-circuiting: the right
+// operand is only evaluated when the left one does not decide the result
+func (e *Evaluator) evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
 
-	switch other.Type() {
-	case object.INTEGER_OBJ:
-		intVal := other.(*object.Integer).Value
-		return &object.String{Value: stringVal + strconv.FormatInt(intVal, 10)}
-	case object.BOOLEAN_OBJ:
-		boolVal := other.(*object.Boolean).Value
-		return &object.String{Value: stringVal + strconv.FormatBool(boolVal)}
-	case object.NULL_OBJ:
-		return &object.String{Value: stringVal + "null"}
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := e.Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
+// evalStringConcatenation evaluates string concatenation where at least one
+// operand is a string, converting the other operand to its string form
+func evalStringConcatenation(left, right object.Object) object.Object {
+	return &object.String{Value: concatString(left) + concatString(right)}
+}
+
+// concatString returns the string form of an operand of string concatenation
+func concatString(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.String:
+		return obj.Value
+	case *object.Integer:
+		return strconv.FormatInt(obj.Value, 10)
+	case *object.Boolean:
+		return strconv.FormatBool(obj.Value)
+	case *object.Null:
+		return "null"
 	default:
-		return &object.String{Value: stringVal + other.Inspect()}
+		return obj.Inspect()
 	}
 }
 
@@ -287,8 +661,14 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal % rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -308,6 +688,41 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+// evalFloatInfixExpression evaluates an infix expression with float operands,
+// promoting an integer operand to float when the operand types are mixed
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
 // evalStringInfixExpression evaluates an infix expression with string operands
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	if operator != "+" {
@@ -320,17 +735,189 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	return &object.String{Value: leftVal + rightVal}
 }
 
+// evalCharInfixExpression evaluates an infix expression with char operands
+func evalCharInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.Char).Value
+	rightVal := right.(*object.Char).Value
+
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+// evalIndexExpression evaluates an index expression
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+// evalMemberExpression looks up a member of a module
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	module, ok := obj.(*object.Module)
+	if !ok {
+		return newError("member access not supported: %s.%s", obj.Type(), name)
+	}
+
+	member, ok := module.Members[name]
+	if !ok {
+		return newError("module %s does not export %s", module.Name, name)
+	}
+	return member
+}
+
+// evalHashLiteral evaluates a hash literal, keeping its pairs in source order
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := e.Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := e.Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash
+}
+
+// evalHashIndexExpression returns the value stored under a key, or null if there is none
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	value, ok := hash.(*object.Hash).Get(key)
+	if !ok {
+		return NULL
+	}
+
+	return value
+}
+
+// evalArrayIndexExpression returns the element at the given position of an array
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	elements := array.(*object.Array).Elements
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 || idx >= int64(len(elements)) {
+		return newError("index out of range: %d (length %d)", idx, len(elements))
+	}
+
+	return elements[idx]
+}
+
+// evalIndexAssignmentExpression evaluates an assignment to an indexed element,
+// updating the indexed collection in place
+func (e *Evaluator) evalIndexAssignmentExpression(node *ast.IndexAssignmentExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Target.Left, env)
+	if isError(left) {
+		return left
+	}
+	index := e.Eval(node.Target.Index, env)
+	if isError(index) {
+		return index
+	}
+	val := e.Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	// The key and value must have the types declared for the contents of a variable
+	if elements := declaredElements(node.Target.Left, left, env); elements != nil {
+		target := ast.Source(node.Target)
+		if len(elements) == 2 && !checkType(index, elements[0]) {
+			return newError("type mismatch for key in assignment to %s: expected %s, got %s%s",
+				target, elements[0], index.Type(), elementMismatch(index, elements[0]))
+		}
+		value := elements[len(elements)-1]
+		if !checkType(val, value) {
+			return newError("type mismatch in assignment to %s: expected %s, got %s%s",
+				target, value, val.Type(), elementMismatch(val, value))
+		}
+	}
+
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		elements := left.(*object.Array).Elements
+		idx := index.(*object.Integer).Value
+		if idx < 0 || idx >= int64(len(elements)) {
+			return newError("index out of range: %d (length %d)", idx, len(elements))
+		}
+		elements[idx] = val
+		return val
+	case left.Type() == object.HASH_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		left.(*object.Hash).Set(key, val)
+		if abort := e.checkAllocation(left); abort != nil {
+			return abort
+		}
+		return val
+	default:
+		return newError("index assignment not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+// evalStringIndexExpression returns the character at the given position of a string.
+// Positions count characters rather than bytes.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 || idx >= int64(len(runes)) {
+		return newError("index out of range: %d (length %d)", idx, len(runes))
+	}
+
+	return &object.Char{Value: runes[idx]}
+}
+
 // evalIfExpression evaluates an if expression
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
@@ -351,11 +938,15 @@ func isTruthy(obj object.Object) bool {
 }
 
 // evalIdentifier evaluates an identifier
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
 
+	if builtin, ok := e.builtins[node.Value]; ok {
+		return builtin
+	}
+
 	if builtin, ok := builtins[node.Value]; ok {
 		return builtin
 	}
@@ -364,11 +955,11 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 }
 
 // evalExpressions evaluates expressions
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -378,12 +969,59 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
+// ApplyFunction calls a function or builtin with already evaluated arguments,
+// checking that a function gets one argument for each parameter, the arguments
+// against the parameter types of an annotated function and the result against
+// its return type
+func (e *Evaluator) ApplyFunction(function object.Object, args []object.Object) object.Object {
+	if fn, ok := function.(*object.Function); ok && len(args) != len(fn.Parameters) {
+		return newError("wrong number of arguments: expected %d, got %d", len(fn.Parameters), len(args))
+	}
+
+	// Check parameter types if function has type annotations
+	if fn, ok := function.(*object.Function); ok && len(fn.ParamTypes) > 0 {
+		for i, paramType := range fn.ParamTypes {
+			if paramType != nil && i < len(args) {
+				if !checkType(args[i], paramType) {
+					return newError("type mismatch for argument %d: expected %s, got %s%s",
+						i, paramType, args[i].Type(), elementMismatch(args[i], paramType))
+				}
+			}
+		}
+	}
+
+	result := e.applyFunction(function, args)
+	if isError(result) {
+		return result
+	}
+
+	// Check return type if function has return type annotation
+	if fn, ok := function.(*object.Function); ok && fn.ReturnType != nil {
+		if !checkType(result, fn.ReturnType) {
+			return newError("return type mismatch: expected %s, got %s%s",
+				fn.ReturnType, result.Type(), elementMismatch(result, fn.ReturnType))
+		}
+	}
+
+	return result
+}
+
 // applyFunction applies a function to arguments
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		maxDepth := e.Limits.MaxCallDepth
+		if maxDepth <= 0 {
+			maxDepth = DefaultMaxCallDepth
+		}
+		if e.depth >= maxDepth {
+			return &object.Abort{Err: &LimitError{Limit: "call depth", Max: int64(maxDepth)}}
+		}
+		e.depth++
+		defer func() { e.depth-- }()
+
 		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
+		evaluated := e.Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
 		return fn.Fn(args...)
@@ -403,34 +1041,99 @@ func extendFunctionEnv(
 		if paramIdx < len(args) {
 			env.Set(param, args[paramIdx])
 		}
+		if paramIdx < len(fn.ParamTypes) {
+			env.SetType(param, fn.ParamTypes[paramIdx])
+		}
 	}
 
 	return env
 }
 
-// unwrapReturnValue unwraps a return value
+// unwrapReturnValue unwraps a return value. A body that produced no value
+// evaluates to NULL.
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
 	}
+	if obj == nil {
+		return NULL
+	}
 
 	return obj
 }
 
 // Helper functions
 
-// nativeBoolToBooleanObject converts a native bool to a Boolean object
-func nativeBoolToBooleanObject(input bool) *object.Boolean {
-	if input {
-		return TRUE
+// inferredType returns the annotation accepting values of the type of a value
+// and null, or nil for null and values that no annotation describes
+func inferredType(obj object.Object) *ast.TypeAnnotation {
+	var name string
+	switch obj.Type() {
+	case object.INTEGER_OBJ:
+		name = "int"
+	case object.FLOAT_OBJ:
+		name = "float"
+	case object.STRING_OBJ:
+		name = "string"
+	case object.CHAR_OBJ:
+		name = "char"
+	case object.BOOLEAN_OBJ:
+		name = "bool"
+	case object.ARRAY_OBJ:
+		name = "array"
+	case object.HASH_OBJ:
+		name = "map"
+	case object.FUNCTION_OBJ, object.BUILTIN_OBJ:
+		name = "function"
+	default:
+		return nil
 	}
-	return FALSE
+
+	return &ast.TypeAnnotation{Value: "union", Options: []*ast.TypeAnnotation{
+		{Value: name},
+		{Value: "null"},
+	}}
 }
 
-// isError checks if an object is an error
+// nameFunction names an anonymous function after the binding it is first
+// assigned to, so stack traces can refer to it
+func nameFunction(val object.Object, name string) {
+	if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+		fn.Name = name
+	}
+}
+
+// nativeBoolToBooleanObject converts a native bool to a Boolean object
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
+// isNumeric checks if an object is an integer or a float
+func isNumeric(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat converts a numeric object to a native float64
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
+// isError checks if an object is an error, or an exit request or abort which
+// stops evaluation the same way
 func isError(obj object.Object) bool {
 	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+		rt := obj.Type()
+		return rt == object.ERROR_OBJ || rt == object.EXIT_OBJ || rt == object.ABORT_OBJ
 	}
 	return false
 }
@@ -451,22 +1154,343 @@ var builtins = map[string]*object.Builtin{
 
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Array:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(arg.Len())}
 			default:
 				return newError("argument to `len` not supported, got %s",
 					args[0].Type())
 			}
 		},
 	},
-	"print": &object.Builtin{
+	"push": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+			if len(args) < 2 {
+				return newError("wrong number of arguments. got=%d, want at least 2",
+					len(args))
 			}
 
-			return NULL
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `push` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			arr.Elements = append(arr.Elements, args[1:]...)
+			return arr
+		},
+	},
+	"pop": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `pop` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			length := len(arr.Elements)
+			if length == 0 {
+				return newError("pop from empty array")
+			}
+
+			last := arr.Elements[length-1]
+			arr.Elements = arr.Elements[:length-1]
+			return last
+		},
+	},
+	"slice": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `slice` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			length := int64(len(arr.Elements))
+			bounds := []int64{0, length}
+			for i, arg := range args[1:] {
+				bound, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("slice bounds must be INTEGER, got %s", arg.Type())
+				}
+				bounds[i] = bound.Value
+			}
+
+			start, end := bounds[0], bounds[1]
+			if start < 0 || end > length || start > end {
+				return newError("slice bounds out of range: [%d:%d] (length %d)",
+					start, end, length)
+			}
+
+			elements := make([]object.Object, end-start)
+			copy(elements, arr.Elements[start:end])
+			return &object.Array{Elements: elements}
+		},
+	},
+	"first": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `first` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			if len(arr.Elements) == 0 {
+				return NULL
+			}
+			return arr.Elements[0]
+		},
+	},
+	"last": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `last` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			length := len(arr.Elements)
+			if length == 0 {
+				return NULL
+			}
+			return arr.Elements[length-1]
 		},
 	},
+	"rest": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `rest` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			length := len(arr.Elements)
+			if length == 0 {
+				return NULL
+			}
+
+			elements := make([]object.Object, length-1)
+			copy(elements, arr.Elements[1:])
+			return &object.Array{Elements: elements}
+		},
+	},
+	"keys": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to `keys` must be HASH, got %s",
+					args[0].Type())
+			}
+
+			keys := []object.Object{}
+			for _, pair := range hash.Ordered() {
+				keys = append(keys, pair.Key)
+			}
+			return &object.Array{Elements: keys}
+		},
+	},
+	"values": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to `values` must be HASH, got %s",
+					args[0].Type())
+			}
+
+			values := []object.Object{}
+			for _, pair := range hash.Ordered() {
+				values = append(values, pair.Value)
+			}
+			return &object.Array{Elements: values}
+		},
+	},
+	"has": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("first argument to `has` must be HASH, got %s",
+					args[0].Type())
+			}
+
+			key, ok := args[1].(object.Hashable)
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			_, found := hash.Get(key)
+			return nativeBoolToBooleanObject(found)
+		},
+	},
+	"delete": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("first argument to `delete` must be HASH, got %s",
+					args[0].Type())
+			}
+
+			key, ok := args[1].(object.Hashable)
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			return nativeBoolToBooleanObject(hash.Delete(key))
+		},
+	},
+	"ord": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Char:
+				return &object.Integer{Value: int64(arg.Value)}
+			default:
+				return newError("argument to `ord` must be CHAR, got %s",
+					args[0].Type())
+			}
+		},
+	},
+	"chr": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				if arg.Value < 0 || arg.Value > utf8.MaxRune || !utf8.ValidRune(rune(arg.Value)) {
+					return newError("argument to `chr` is not a valid character code: %d",
+						arg.Value)
+				}
+				return &object.Char{Value: rune(arg.Value)}
+			default:
+				return newError("argument to `chr` must be INTEGER, got %s",
+					args[0].Type())
+			}
+		},
+	},
+	"exit": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newError("wrong number of arguments. got=%d, want=0 or 1",
+					len(args))
+			}
+			if len(args) == 0 {
+				return &object.Exit{Code: 0}
+			}
+			code, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("argument to `exit` must be INTEGER, got %s",
+					args[0].Type())
+			}
+			return &object.Exit{Code: int(code.Value)}
+		},
+	},
+}
+
+// print writes each argument on its own line to Stdout
+func (e *Evaluator) print(args ...object.Object) object.Object {
+	return writeLines(e.Stdout, args)
+}
+
+// eprint writes each argument on its own line to Stderr
+func (e *Evaluator) eprint(args ...object.Object) object.Object {
+	return writeLines(e.Stderr, args)
+}
+
+// writeLines writes the printed form of each object on its own line
+func writeLines(w io.Writer, args []object.Object) object.Object {
+	for _, arg := range args {
+		if _, err := fmt.Fprintln(w, arg.Inspect()); err != nil {
+			return newError("could not write output: %s", err)
+		}
+	}
+	return NULL
+}
+
+// input reads a line from Stdin, after writing an optional prompt to Stdout.
+// It returns the line without its line ending, or null at the end of the input.
+func (e *Evaluator) input(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1",
+			len(args))
+	}
+	if len(args) == 1 {
+		prompt, ok := args[0].(*object.String)
+		if !ok {
+			return newError("argument to `input` must be STRING, got %s",
+				args[0].Type())
+		}
+		if _, err := io.WriteString(e.Stdout, prompt.Value); err != nil {
+			return newError("could not write output: %s", err)
+		}
+	}
+
+	if e.stdin == nil || e.stdinSource != e.Stdin {
+		e.stdin = bufio.NewReader(e.Stdin)
+		e.stdinSource = e.Stdin
+	}
+
+	line, err := e.stdin.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return NULL
+	}
+	if err != nil && err != io.EOF {
+		return newError("could not read input: %s", err)
+	}
+
+	return &object.String{Value: strings.TrimRight(line, "\r\n")}
 }
 
 // Helper function to extract parameter names from FunctionParameters
@@ -479,144 +1503,374 @@ func extractParameterNames(params []*ast.FunctionParameter) []string {
 }
 
 // Helper function to extract parameter types from FunctionParameters
-func extractParameterTypes(params []*ast.FunctionParameter) []string {
-	types := []string{}
+func extractParameterTypes(params []*ast.FunctionParameter) []*ast.TypeAnnotation {
+	types := []*ast.TypeAnnotation{}
 	for _, param := range params {
-		if param.Type != nil {
-			types = append(types, param.Type.Value)
+		types = append(types, param.Type)
+	}
+	return types
+}
+
+// matchesShape reports whether the object is an array or map as t expects,
+// whatever its contents
+func matchesShape(obj object.Object, t *ast.TypeAnnotation) bool {
+	switch t.Value {
+	case "array":
+		return obj.Type() == object.ARRAY_OBJ
+	case "map":
+		return obj.Type() == object.HASH_OBJ
+	}
+	return false
+}
+
+// declaredElements returns the types declared for the contents of the
+// array or map obj when node is a variable declared with them: the element
+// type of an array, or the key and value types of a map
+func declaredElements(node ast.Expression, obj object.Object, env *object.Environment) []*ast.TypeAnnotation {
+	ident, ok := node.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	declared := env.TypeOf(ident.Value)
+	if declared == nil {
+		return nil
+	}
+
+	options := []*ast.TypeAnnotation{declared}
+	if declared.IsUnion() {
+		options = declared.Options
+	}
+	for _, option := range options {
+		if matchesShape(obj, option) {
+			return option.Args
+		}
+	}
+	return nil
+}
+
+// elementMismatch describes the first element of an array or map whose type
+// does not match the type arguments of t, with the path of indexes and keys
+// leading to it - e.g., " (at key a, index 2: expected int, got STRING)". It
+// returns an empty string when every element matches or the value is not an
+// array or map of the shape t expects.
+func elementMismatch(obj object.Object, t *ast.TypeAnnotation) string {
+	path, expected, got := findElementMismatch(obj, t)
+	if path == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (at %s: expected %s, got %s)", strings.Join(path, ", "), expected, got.Type())
+}
+
+// findElementMismatch returns the path to the first element of obj that does
+// not match its type in t, the type expected there and the element found
+func findElementMismatch(obj object.Object, t *ast.TypeAnnotation) ([]string, *ast.TypeAnnotation, object.Object) {
+	if t.IsUnion() {
+		// Describe the contents of the value against the first option of its shape
+		for _, option := range t.Options {
+			if matchesShape(obj, option) {
+				return findElementMismatch(obj, option)
+			}
+		}
+		return nil, nil, nil
+	}
+	if t.Args == nil || !matchesShape(obj, t) {
+		return nil, nil, nil
+	}
+
+	// mismatch returns the path to an element that does not have type expected
+	mismatch := func(step string, element object.Object, expected *ast.TypeAnnotation) ([]string, *ast.TypeAnnotation, object.Object) {
+		if checkType(element, expected) {
+			return nil, nil, nil
+		}
+		if path, inner, got := findElementMismatch(element, expected); path != nil {
+			return append([]string{step}, path...), inner, got
+		}
+		return []string{step}, expected, element
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		for i, element := range obj.Elements {
+			if path, expected, got := mismatch(fmt.Sprintf("index %d", i), element, t.Args[0]); path != nil {
+				return path, expected, got
+			}
+		}
+	case *object.Hash:
+		for _, pair := range obj.Ordered() {
+			step := "key " + pair.Key.Inspect()
+			if path, expected, got := mismatch(step, pair.Key, t.Args[0]); path != nil {
+				return path, expected, got
+			}
+			if path, expected, got := mismatch(step, pair.Value, t.Args[1]); path != nil {
+				return path, expected, got
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// coversType reports whether every type accepted by b matches a type
+// accepted by a
+func coversType(a, b *ast.TypeAnnotation) bool {
+	options := []*ast.TypeAnnotation{b}
+	if b.IsUnion() {
+		options = b.Options
+	}
+
+	for _, option := range options {
+		covered := false
+		if a.IsUnion() {
+			for _, candidate := range a.Options {
+				covered = covered || sameType(candidate, option)
+			}
 		} else {
-			types = append(types, "")
+			covered = sameType(a, option)
+		}
+		if !covered {
+			return false
 		}
 	}
-	return types
+	return true
 }
 
-// checkType verifies if the object matches the expected type
-func checkType(obj object.Object, typeName string) bool {
-	switch typeName {
+// checkType verifies if the object matches the expected type. An array or map
+// type with type arguments only accepts values whose contents have those
+// types, a function type with a signature only accepts functions whose
+// annotations agree with it, and a union accepts values matching any of its
+// options.
+func checkType(obj object.Object, t *ast.TypeAnnotation) bool {
+	if t.IsUnion() {
+		for _, option := range t.Options {
+			if checkType(obj, option) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch t.Value {
+	case "null":
+		return obj == NULL
 	case "int":
 		return obj.Type() == object.INTEGER_OBJ
+	case "float":
+		return obj.Type() == object.FLOAT_OBJ
 	case "string":
 		return obj.Type() == object.STRING_OBJ
+	case "char":
+		return obj.Type() == object.CHAR_OBJ
+	case "array", "map":
+		return matchesShape(obj, t) && elementMismatch(obj, t) == ""
 	case "bool":
 		return obj.Type() == object.BOOLEAN_OBJ
 	case "function":
-		return obj.Type() == object.FUNCTION_OBJ
+		switch fn := obj.(type) {
+		case *object.Function:
+			return !t.IsSignature() || matchesSignature(t, len(fn.Parameters), fn.ParamTypes, fn.ReturnType)
+		case *object.Builtin:
+			// A builtin without a declared signature may take any arguments
+			return !t.IsSignature() || fn.ParamTypes == nil ||
+				matchesSignature(t, len(fn.ParamTypes), fn.ParamTypes, fn.ReturnType)
+		default:
+			return false
+		}
 	default:
 		return true // Unknown types are accepted for now
 	}
 }
 
-// evalImportStatement imports and evaluates a file
-func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
-	filePath := node.Path.Value
+// matchesSignature reports whether a function with the given number of
+// parameters, parameter types and return type has the signature t. Like
+// values of unknown type, parameters and results without annotations match
+// any type.
+func matchesSignature(t *ast.TypeAnnotation, arity int, params []*ast.TypeAnnotation, result *ast.TypeAnnotation) bool {
+	if arity != len(t.Params) {
+		return false
+	}
+	for i, param := range params {
+		if !sameType(param, t.Params[i]) {
+			return false
+		}
+	}
+	return sameType(result, t.Return)
+}
+
+// sameType reports whether two annotations name the same type. A missing
+// annotation, or an array, map or function type that does not describe its
+// contents or signature, matches its counterpart, and unions match when each option of one matches an option of
+// the other.
+func sameType(a, b *ast.TypeAnnotation) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	if a.IsUnion() || b.IsUnion() {
+		return coversType(a, b) && coversType(b, a)
+	}
+	if a.Value != b.Value {
+		return false
+	}
+	if a.Args != nil && b.Args != nil {
+		if len(a.Args) != len(b.Args) {
+			return false
+		}
+		for i := range a.Args {
+			if !sameType(a.Args[i], b.Args[i]) {
+				return false
+			}
+		}
+	}
+	if !a.IsSignature() || !b.IsSignature() {
+		return true
+	}
+	if len(a.Params) != len(b.Params) {
+		return false
+	}
+	for i := range a.Params {
+		if !sameType(a.Params[i], b.Params[i]) {
+			return false
+		}
+	}
+	return sameType(a.Return, b.Return)
+}
+
+// evalImportStatement imports and evaluates a file, then binds what it exports:
+// every exported name for a plain import, a module object for `import "x" as m;`, or the
+// listed names for `import { a, b } from "x";`
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	importPath := node.Path.Value
 
 	// Check if the file has a .gct extension, add if needed
-	if !strings.HasSuffix(filePath, ".gct") {
-		filePath = filePath + ".gct"
+	if !strings.HasSuffix(importPath, ".gct") {
+		importPath = importPath + ".gct"
 	}
 
-	fmt.Printf("Importing file: %s\n", filePath)
+	importedEnv, errObj := e.importFile(importPath, env)
+	if errObj != nil {
+		return errObj
+	}
+	exports := importedEnv.Exports()
 
-	// Use absolute path for tracking imports
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		return newError("could not resolve absolute path: %s", err.Error())
+	switch {
+	case node.Alias != nil:
+		if env.Has(node.Alias.Value) {
+			return newError("cannot import %s as %s: %s is already defined", importPath, node.Alias.Value, node.Alias.Value)
+		}
+		env.SetConst(node.Alias.Value, &object.Module{Name: importPath, Members: exports})
+	case node.Names != nil:
+		for _, name := range node.Names {
+			if importedEnv.Has(name.Value) && !importedEnv.IsExported(name.Value) {
+				return newError("cannot import %s from %s: it is not exported", name.Value, importPath)
+			}
+			if _, ok := exports[name.Value]; !ok {
+				return newError("cannot import %s from %s: it is not defined there", name.Value, importPath)
+			}
+			if env.Has(name.Value) {
+				return newError("cannot import %s from %s: %s is already defined", name.Value, importPath, name.Value)
+			}
+		}
+		for _, name := range node.Names {
+			env.SetConst(name.Value, exports[name.Value])
+		}
+	default:
+		names := make([]string, 0, len(exports))
+		for name := range exports {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if env.Has(name) {
+				return newError("cannot import %s from %s: %s is already defined", name, importPath, name)
+			}
+		}
+		// Copy the exported variables from imported environment to the current environment
+		importedEnv.ExportTo(env)
 	}
 
-	fmt.Printf("Absolute path: %s\n", absPath)
+	return NULL
+}
 
-	// Check for circular imports
-	if importedFiles[absPath] {
-		// File is already being imported, skip to prevent circularity
-		fmt.Printf("Skipping circular import of %s\n", filePath)
-		return NULL
+// entryName returns the name of the file evaluation started from, cleaned the
+// way the resolver cleans the names of the files it resolves, so that an import
+// of the entry file is recognized as a cycle
+func (e *Evaluator) entryName(file string) string {
+	if file == "" {
+		return file
+	}
+	if _, ok := e.Resolver.(*FileResolver); ok {
+		return filepath.Clean(file)
 	}
+	return path.Clean(filepath.ToSlash(file))
+}
 
-	// Mark file as being imported
-	importedFiles[absPath] = true
-	defer func() {
-		// After processing, unmark the file to allow it to be imported again in other contexts
-		importedFiles[absPath] = false
-	}()
+// importFile returns the environment holding what an imported file defines.
+// The file is resolved, parsed and evaluated the first time it is imported and
+// taken from the cache afterwards. Importing a file whose own imports are still
+// being evaluated is an import cycle, reported with the chain of imports.
+func (e *Evaluator) importFile(importPath string, env *object.Environment) (*object.Environment, object.Object) {
+	e.debugf("Importing file: %s", importPath)
 
-	var input string
-	var loadedFrom string
+	name, input, err := e.Resolver.Resolve(importPath, env.File())
+	if err != nil {
+		return nil, newError("could not import %s: %s", importPath, err)
+	}
 
-	// Check cache first
-	if cachedInput, ok := importCache[absPath]; ok {
-		input = cachedInput
-		loadedFrom = "cache"
-	} else {
-		// First, try to read from the current directory
-		fileBytes, err := os.ReadFile(filePath)
-		if err != nil {
-			// If not found, try the examples directory
-			examplesPath := filepath.Join("examples", filePath)
-			fmt.Printf("Trying examples path: %s\n", examplesPath)
-			fileBytes, err = os.ReadFile(examplesPath)
-			if err != nil {
-				// Try with just the basename in examples directory
-				baseName := filepath.Base(filePath)
-				examplesPath = filepath.Join("examples", baseName)
-				fmt.Printf("Trying examples path with basename: %s\n", examplesPath)
-				fileBytes, err = os.ReadFile(examplesPath)
-				if err != nil {
-					return newError("could not import file: %s. Tried: %s, %s, and %s",
-						err.Error(), filePath, filepath.Join("examples", filePath), examplesPath)
-				}
-				loadedFrom = "examples with basename"
-			} else {
-				loadedFrom = "examples"
-			}
+	e.debugf("Resolved %s to %s, length: %d bytes", importPath, name, len(input))
 
-			// Update absPath to the examples path
-			absPath, _ = filepath.Abs(examplesPath)
-		} else {
-			loadedFrom = "current directory"
+	if len(e.importing) == 0 {
+		e.importing = append(e.importing, e.entryName(env.File()))
+		defer func() { e.importing = e.importing[:0] }()
+	}
+	for idx, importing := range e.importing {
+		if importing == name {
+			chain := append(append([]string{}, e.importing[idx:]...), name)
+			return nil, newError("import cycle: %s", strings.Join(chain, " → "))
 		}
+	}
 
-		input = string(fileBytes)
-		fmt.Printf("Loaded file from: %s, length: %d bytes\n", loadedFrom, len(input))
-
-		// Cache the file content
-		importCache[absPath] = input
+	if cached, ok := e.modules[name]; ok {
+		e.debugf("Using cached module %s", name)
+		return cached, nil
 	}
 
+	e.importing = append(e.importing, name)
+	defer func() { e.importing = e.importing[:len(e.importing)-1] }()
+
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 
 	if len(p.Errors()) > 0 {
 		var errMsg strings.Builder
-		errMsg.WriteString(fmt.Sprintf("parser errors in imported file %s (loaded from %s):\n", filePath, loadedFrom))
+		errMsg.WriteString(fmt.Sprintf("parser errors in imported file %s:\n", name))
 		for _, msg := range p.Errors() {
 			errMsg.WriteString(fmt.Sprintf("\t%s\n", msg))
 		}
-		fmt.Printf("First few characters of file: %q\n", input[:min(20, len(input))])
-		return newError(errMsg.String())
+		return nil, newError("%s", errMsg.String())
 	}
 
-	// Create new enclosed environment for the imported file
-	importedEnv := object.NewEnclosedEnvironment(env)
+	// Like the file that imports it, the file is type checked before it runs
+	if errors := typecheck.Check(program, env.Root().Names()...); len(errors) > 0 {
+		var errMsg strings.Builder
+		errMsg.WriteString(fmt.Sprintf("type errors in imported file %s:\n", name))
+		for _, err := range errors {
+			errMsg.WriteString(fmt.Sprintf("\t%s\n", err))
+		}
+		return nil, newError("%s", errMsg.String())
+	}
+
+	// The file sees the values registered by the host, but not the variables
+	// of whichever file happened to import it first
+	importedEnv := object.NewEnclosedEnvironment(env.Root())
+	importedEnv.SetFile(name)
 
 	// Evaluate the imported program
-	result := Eval(program, importedEnv)
+	result := e.Eval(program, importedEnv)
 	if isError(result) {
-		return result
+		return nil, result
 	}
 
-	// Copy all variables from imported environment to the current environment
-	importedEnv.ExportTo(env)
+	e.modules[name] = importedEnv
+	e.debugf("Successfully imported: %s", name)
 
-	fmt.Printf("Successfully imported: %s\n", filePath)
-	return NULL
-}
-
-// min returns the smaller of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	return importedEnv, nil
 }
@@ -0,0 +1,124 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ImportResolver finds and reads the files scripts import
+type ImportResolver interface {
+	// Resolve locates the file imported as importPath by the file named from,
+	// which is empty or a name like "<repl>" for code that is not in a file. It
+	// returns a name that identifies the file, used to detect repeated imports
+	// and as the file name in error messages, along with the file's source.
+	Resolve(importPath, from string) (name string, source string, err error)
+}
+
+// FileResolver resolves imports on the file system. An absolute path is used
+// as is; a relative path is looked up next to the importing file first, then
+// in each directory of SearchPath in turn.
+type FileResolver struct {
+	SearchPath []string
+}
+
+// NewSearchPathResolver returns a FileResolver whose search path is taken from
+// the GOCEPTION_PATH environment variable, a list of directories separated like
+// PATH
+func NewSearchPathResolver() *FileResolver {
+	return &FileResolver{SearchPath: filepath.SplitList(os.Getenv("GOCEPTION_PATH"))}
+}
+
+// Resolve implements ImportResolver
+func (r *FileResolver) Resolve(importPath, from string) (string, string, error) {
+	var candidates []string
+	if filepath.IsAbs(importPath) {
+		candidates = []string{importPath}
+	} else {
+		candidates = append(candidates, filepath.Join(filepath.Dir(from), importPath))
+		for _, dir := range r.SearchPath {
+			candidates = append(candidates, filepath.Join(dir, importPath))
+		}
+	}
+
+	for _, candidate := range candidates {
+		source, err := os.ReadFile(candidate)
+		if err == nil {
+			return filepath.Clean(candidate), string(source), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", err
+		}
+	}
+
+	return "", "", notFound(candidates)
+}
+
+// FSResolver resolves imports in a file system such as an embed.FS. Paths use
+// forward slashes; a relative path is looked up next to the importing file and
+// an absolute one from the root of the file system.
+type FSResolver struct {
+	FS fs.FS
+}
+
+// Resolve implements ImportResolver
+func (r *FSResolver) Resolve(importPath, from string) (string, string, error) {
+	name, err := slashPath(importPath, from)
+	if err != nil {
+		return "", "", err
+	}
+
+	source, err := fs.ReadFile(r.FS, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", notFound([]string{name})
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return name, string(source), nil
+}
+
+// MapResolver resolves imports from sources held in memory, keyed by their
+// slash-separated paths. Paths are resolved as by FSResolver.
+type MapResolver map[string]string
+
+// Resolve implements ImportResolver
+func (r MapResolver) Resolve(importPath, from string) (string, string, error) {
+	name, err := slashPath(importPath, from)
+	if err != nil {
+		return "", "", err
+	}
+
+	source, ok := r[name]
+	if !ok {
+		return "", "", notFound([]string{name})
+	}
+
+	return name, source, nil
+}
+
+// slashPath resolves a slash-separated import path against the directory of
+// the importing file, giving a path valid for fs.FS
+func slashPath(importPath, from string) (string, error) {
+	var name string
+	if strings.HasPrefix(importPath, "/") {
+		name = path.Clean(strings.TrimPrefix(importPath, "/"))
+	} else {
+		name = path.Join(path.Dir(filepath.ToSlash(from)), importPath)
+	}
+
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("invalid import path: %s", importPath)
+	}
+	return name, nil
+}
+
+// notFound reports that none of the candidate paths for an import exist
+func notFound(candidates []string) error {
+	return fmt.Errorf("%w (tried %s)", fs.ErrNotExist, strings.Join(candidates, ", "))
+}
@@ -0,0 +1,90 @@
+package goception
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/onurravli/goception/object"
+)
+
+// Function is a script function or builtin that Go code can call. Functions
+// keep the environment they were defined in, so they can be called any number
+// of times after the script that defined them has finished running.
+//
+// A registered Go function can take a *Function parameter to receive a
+// callback from a script:
+//
+//	interp.Register("onEvent", func(handler *goception.Function) { handlers = append(handlers, handler) })
+type Function struct {
+	interp *Interpreter
+	fn     object.Object
+}
+
+var functionType = reflect.TypeOf((*Function)(nil))
+
+// Function returns the global function or builtin bound to name
+func (i *Interpreter) Function(name string) (*Function, error) {
+	obj, ok := i.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("goception: %s is not defined", name)
+	}
+
+	switch obj.(type) {
+	case *object.Function, *object.Builtin:
+		return &Function{interp: i, fn: obj}, nil
+	default:
+		return nil, fmt.Errorf("goception: %s is not a function, got %s", name, obj.Type())
+	}
+}
+
+// Call calls the function with Go arguments, converted as by
+// Interpreter.Register. Arguments are checked against the parameter types of
+// an annotated function and the result against its return type.
+func (f *Function) Call(args ...interface{}) (object.Object, error) {
+	return f.CallContext(context.Background(), args...)
+}
+
+// CallContext is like Call, but stops the call when ctx is done. When the
+// function is called back from a Go function registered with the interpreter,
+// the call is bounded by the context and step budget of the script that called
+// the Go function instead.
+func (f *Function) CallContext(ctx context.Context, args ...interface{}) (object.Object, error) {
+	objects := make([]object.Object, len(args))
+	for i, arg := range args {
+		obj, err := f.interp.toObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("goception: argument %d: %w", i, err)
+		}
+		objects[i] = obj
+	}
+
+	return result(f.interp.eval.Call(ctx, f.fn, objects))
+}
+
+// Object returns the script value of the function
+func (f *Function) Object() object.Object {
+	return f.fn
+}
+
+// Decode converts a script value, such as the result of Run or Call, to the Go
+// value target points to. It converts values the same way as the arguments
+// of a registered function; an empty interface receives int64, float64,
+// rune, string, bool, []interface{} or a map.
+//
+//	var names []string
+//	err := interp.Decode(result, &names)
+func (i *Interpreter) Decode(obj object.Object, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("goception: Decode target must be a non-nil pointer, got %T", target)
+	}
+
+	converted, err := i.fromObject(obj, v.Type().Elem())
+	if err != nil {
+		return fmt.Errorf("goception: value %s", err)
+	}
+
+	v.Elem().Set(converted)
+	return nil
+}
@@ -0,0 +1,300 @@
+// Package goception embeds the Goception scripting language in Go programs.
+//
+// An Interpreter keeps the global bindings of the scripts it runs, so a host
+// can run a script, then read the values it defined or call its functions:
+//
+//	interp := goception.New()
+//	if _, err := interp.Run(ctx, `const double = function(x: int): int { return x * 2; };`, "double.gct"); err != nil {
+//		return err
+//	}
+//	result, err := interp.Call("double", 21)
+package goception
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/onurravli/goception/ast"
+	"github.com/onurravli/goception/evaluator"
+	"github.com/onurravli/goception/lexer"
+	"github.com/onurravli/goception/object"
+	"github.com/onurravli/goception/parser"
+	"github.com/onurravli/goception/typecheck"
+)
+
+// Interpreter runs Goception scripts in a shared global environment
+//
+// Each interpreter has its own globals, imports and evaluation state, so
+// separate interpreters can run in parallel goroutines. A single interpreter
+// must not be used by more than one goroutine at a time.
+type Interpreter struct {
+	eval *evaluator.Evaluator
+	host *object.Environment // values registered by the host program
+	env  *object.Environment // globals defined by scripts, enclosed by host
+}
+
+// Option configures an Interpreter
+type Option func(*Interpreter)
+
+// New creates an interpreter with an empty global environment
+func New(options ...Option) *Interpreter {
+	host := object.NewEnvironment()
+	interp := &Interpreter{
+		eval: evaluator.New(),
+		host: host,
+		env:  object.NewEnclosedEnvironment(host),
+	}
+
+	for _, option := range options {
+		option(interp)
+	}
+
+	return interp
+}
+
+// ImportResolver finds and reads the files scripts import. Paths are resolved
+// relative to the importing file.
+type ImportResolver = evaluator.ImportResolver
+
+// FileResolver resolves imports on the file system, next to the importing file
+// and then in the directories of its SearchPath
+type FileResolver = evaluator.FileResolver
+
+// FSResolver resolves imports in an fs.FS, such as an embed.FS
+type FSResolver = evaluator.FSResolver
+
+// MapResolver resolves imports from sources held in memory, keyed by path
+type MapResolver = evaluator.MapResolver
+
+// NewSearchPathResolver returns a FileResolver that searches the directories
+// listed in the GOCEPTION_PATH environment variable. It is the default resolver.
+func NewSearchPathResolver() *FileResolver {
+	return evaluator.NewSearchPathResolver()
+}
+
+// WithImportResolver sets how imports are found and read
+func WithImportResolver(resolver ImportResolver) Option {
+	return func(i *Interpreter) {
+		i.eval.Resolver = resolver
+	}
+}
+
+// WithStdout sets where print writes. It defaults to os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.eval.Stdout = w
+	}
+}
+
+// WithStderr sets where eprint writes. It defaults to os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.eval.Stderr = w
+	}
+}
+
+// WithStdin sets where input reads from. It defaults to os.Stdin.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) {
+		i.eval.Stdin = r
+	}
+}
+
+// WithDebugLogger logs diagnostics, such as how imports are resolved, to logger
+func WithDebugLogger(logger *log.Logger) Option {
+	return func(i *Interpreter) {
+		i.eval.Debug = logger
+	}
+}
+
+// WithMaxCallDepth limits the number of nested function calls. Without it,
+// calls nest at most evaluator.DefaultMaxCallDepth deep.
+func WithMaxCallDepth(depth int) Option {
+	return func(i *Interpreter) {
+		i.eval.Limits.MaxCallDepth = depth
+	}
+}
+
+// WithMaxSteps limits the number of AST nodes a single Run or Call may evaluate
+func WithMaxSteps(steps int64) Option {
+	return func(i *Interpreter) {
+		i.eval.Limits.MaxSteps = steps
+	}
+}
+
+// WithMaxAllocation limits the length of the strings (in bytes), arrays and
+// hashes a script may create
+func WithMaxAllocation(size int) Option {
+	return func(i *Interpreter) {
+		i.eval.Limits.MaxAllocation = size
+	}
+}
+
+// Run parses, type checks and evaluates source, and returns the value of its last statement.
+// The filename is only used in error messages. Bindings defined by the script
+// stay in the interpreter's global environment for later calls.
+//
+// A syntax error is returned as a *ParseError, type errors found before
+// evaluation as a *TypeError, a runtime error as a
+// *RuntimeError, and a call to the exit builtin as an *ExitError. When ctx is
+// done, evaluation stops and ctx.Err() is returned; when a limit is exceeded,
+// evaluation stops with a *LimitError.
+func (i *Interpreter) Run(ctx context.Context, source, filename string) (object.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	program, err := parse(source, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Globals from earlier runs and registered values are defined, but of unknown type
+	predeclared := append(i.env.Names(), i.host.Names()...)
+	if errors := typecheck.Check(program, predeclared...); len(errors) != 0 {
+		return nil, &TypeError{Filename: filename, Source: source, Errors: errors}
+	}
+
+	i.env.SetFile(filename)
+	return result(i.eval.Run(ctx, program, i.env))
+}
+
+// Check parses and type checks a script and the files it imports without
+// running them, resolving imports with the default resolver. It returns a
+// *ParseError or a *TypeError for the first file that is not valid.
+func Check(source, filename string) error {
+	return New().Check(source, filename)
+}
+
+// Check parses and type checks a script and the files it imports without
+// running them, resolving imports as Run does. It returns a *ParseError or a
+// *TypeError for the first file that is not valid, or an error if an import
+// cannot be resolved.
+func (i *Interpreter) Check(source, filename string) error {
+	return i.check(source, filename, map[string]bool{})
+}
+
+// check checks a file and then each file it imports that is not in checked yet
+func (i *Interpreter) check(source, filename string, checked map[string]bool) error {
+	program, err := parse(source, filename)
+	if err != nil {
+		return err
+	}
+
+	errors, imports := typecheck.CheckImports(program, i.host.Names()...)
+	if len(errors) != 0 {
+		return &TypeError{Filename: filename, Source: source, Errors: errors}
+	}
+
+	for _, stmt := range imports {
+		importPath := stmt.Path.Value
+		if !strings.HasSuffix(importPath, ".gct") {
+			importPath += ".gct"
+		}
+
+		name, source, err := i.eval.Resolver.Resolve(importPath, filename)
+		if err != nil {
+			return fmt.Errorf("%s: could not import %s: %w", filename, importPath, err)
+		}
+		if checked[name] {
+			continue
+		}
+		checked[name] = true
+
+		if err := i.check(source, name, checked); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parse parses a script, returning a *ParseError if it has syntax errors
+func parse(source, filename string) (*ast.Program, error) {
+	l := lexer.New(source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.ParseErrors()) != 0 {
+		return nil, &ParseError{Filename: filename, Source: source, Errors: p.ParseErrors()}
+	}
+	return program, nil
+}
+
+// Call calls the global function or builtin bound to name with the given
+// arguments, converted as by Register
+func (i *Interpreter) Call(name string, args ...interface{}) (object.Object, error) {
+	fn, err := i.Function(name)
+	if err != nil {
+		return nil, err
+	}
+	return fn.Call(args...)
+}
+
+// Get returns the value of a global binding
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// Set binds a global variable to value. The value may be an object.Object or
+// any Go value Register accepts, which is converted to the matching script value.
+func (i *Interpreter) Set(name string, value interface{}) error {
+	obj, err := i.toObject(value)
+	if err != nil {
+		return fmt.Errorf("goception: %s: %w", name, err)
+	}
+
+	i.env.Set(name, obj)
+	return nil
+}
+
+// Register makes a Go function or value available to scripts under name, like
+// a builtin: scripts cannot reassign it, but may shadow it with their own binding.
+//
+// Go values are converted to script values: bools to BOOLEAN, integers of any
+// size (including runes) to INTEGER, floats to FLOAT, strings to STRING, slices
+// and arrays to ARRAY, maps to HASH with pairs ordered by key, nil pointers,
+// slices and maps to null, and other pointers to the value they point to.
+//
+// A function is called with its arguments converted back to its parameter
+// types, failing with a script error on a wrong number or type of arguments. A
+// char is accepted for a rune parameter and an integer for a float one. The
+// function may return nothing, one value, an error, or a value and an error; a
+// non-nil error or a panic in the function becomes a script error.
+//
+//	interp.Register("shout", func(s string, times int) (string, error) { ... })
+func (i *Interpreter) Register(name string, value interface{}) error {
+	var obj object.Object
+	var err error
+
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Func && !v.IsNil() {
+		obj, err = i.wrapFunction(name, v)
+	} else {
+		obj, err = i.toObject(value)
+	}
+	if err != nil {
+		return fmt.Errorf("goception: %s: %w", name, err)
+	}
+
+	i.host.SetConst(name, obj)
+	return nil
+}
+
+// result turns the outcome of an evaluation into the values returned by Run and Call
+func result(obj object.Object) (object.Object, error) {
+	switch obj := obj.(type) {
+	case *object.Error:
+		return nil, &RuntimeError{Err: obj}
+	case *object.Exit:
+		return nil, &ExitError{Code: obj.Code}
+	case *object.Abort:
+		return nil, obj.Err
+	case nil:
+		return evaluator.NULL, nil
+	default:
+		return obj, nil
+	}
+}
@@ -0,0 +1,1082 @@
+package goception
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/onurravli/goception/object"
+)
+
+func TestRunKeepsGlobals(t *testing.T) {
+	interp := New()
+	ctx := context.Background()
+
+	if _, err := interp.Run(ctx, `var total: int = 40;`, "first.gct"); err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+	result, err := interp.Run(ctx, `total + 2;`, "second.gct")
+	if err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+
+	testInteger(t, result, 42)
+}
+
+func TestRunParseError(t *testing.T) {
+	_, err := New().Run(context.Background(), "print(1;", "broken.gct")
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected *ParseError, got %T (%v)", err, err)
+	}
+	if len(parseErr.Errors) != 1 || parseErr.Errors[0].Line != 1 || parseErr.Errors[0].Column != 8 {
+		t.Errorf("unexpected parse errors: %v", parseErr.Errors)
+	}
+
+	expected := "broken.gct: line 1, column 8: expected next token to be ), got ; instead"
+	if err.Error() != expected {
+		t.Errorf("wrong message. expected=%q, got=%q", expected, err.Error())
+	}
+}
+
+func TestRunRuntimeError(t *testing.T) {
+	source := "const add = function(a, b) {\n  return a + b;\n};\nadd(1, true);"
+	_, err := New().Run(context.Background(), source, "add.gct")
+
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected *RuntimeError, got %T (%v)", err, err)
+	}
+
+	expected := "add.gct:2:12: type mismatch: INTEGER + BOOLEAN"
+	if err.Error() != expected {
+		t.Errorf("wrong message. expected=%q, got=%q", expected, err.Error())
+	}
+	if !strings.Contains(runtimeErr.Trace(), "at add.gct:4:1") {
+		t.Errorf("trace does not include the call site: %s", runtimeErr.Trace())
+	}
+}
+
+func TestRunExit(t *testing.T) {
+	_, err := New().Run(context.Background(), `exit(3); print("unreachable");`, "exit.gct")
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit status 3, got %v", err)
+	}
+}
+
+func TestRunCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := New().Run(ctx, `1;`, "cancelled.gct")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	interp := New()
+	source := `const double = function(x: int): int { return x * 2; };`
+	if _, err := interp.Run(context.Background(), source, "double.gct"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	result, err := interp.Call("double", 21)
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	testInteger(t, result, 42)
+
+	_, err = interp.Call("double", "21")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected *RuntimeError, got %T (%v)", err, err)
+	}
+	if runtimeErr.Err.Message != "type mismatch for argument 0: expected int, got STRING" {
+		t.Errorf("wrong message: %q", runtimeErr.Err.Message)
+	}
+
+	_, err = interp.Call("double", 1, 2)
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected *RuntimeError, got %T (%v)", err, err)
+	}
+	if runtimeErr.Err.Message != "wrong number of arguments: expected 1, got 2" {
+		t.Errorf("wrong message: %q", runtimeErr.Err.Message)
+	}
+
+	if _, err := interp.Call("missing"); err == nil {
+		t.Errorf("expected an error calling an undefined function")
+	}
+}
+
+func TestGetAndSet(t *testing.T) {
+	interp := New()
+
+	if err := interp.Set("greeting", "hello"); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := interp.Set("unsupported", struct{}{}); err == nil {
+		t.Errorf("expected an error setting an unsupported value")
+	}
+
+	if _, err := interp.Run(context.Background(), `var shout = greeting + "!";`, "set.gct"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	value, ok := interp.Get("shout")
+	if !ok {
+		t.Fatalf("shout is not defined")
+	}
+	str, ok := value.(*object.String)
+	if !ok || str.Value != "hello!" {
+		t.Errorf("wrong value for shout: %s", value.Inspect())
+	}
+
+	if _, ok := interp.Get("missing"); ok {
+		t.Errorf("expected missing to be undefined")
+	}
+}
+
+func testInteger(t *testing.T, obj object.Object, expected int64) {
+	t.Helper()
+
+	integer, ok := obj.(*object.Integer)
+	if !ok {
+		t.Fatalf("object is not Integer. got=%T (%+v)", obj, obj)
+	}
+	if integer.Value != expected {
+		t.Errorf("wrong value. expected=%d, got=%d", expected, integer.Value)
+	}
+}
+
+func TestRegisterFunction(t *testing.T) {
+	interp := New()
+	register := func(name string, fn interface{}) {
+		t.Helper()
+		if err := interp.Register(name, fn); err != nil {
+			t.Fatalf("register %s failed: %v", name, err)
+		}
+	}
+
+	register("repeat", func(s string, n int64) string { return strings.Repeat(s, int(n)) })
+	register("isLong", func(s string, limit int) (bool, error) {
+		if limit < 0 {
+			return false, errors.New("limit must not be negative")
+		}
+		return len(s) > limit, nil
+	})
+	register("sum", func(values ...float64) float64 {
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		return total
+	})
+	register("total", func(prices map[string]int) int {
+		total := 0
+		for _, price := range prices {
+			total += price
+		}
+		return total
+	})
+	register("split", func(s string) []string { return strings.Split(s, ",") })
+	register("upper", func(r rune) string { return strings.ToUpper(string(r)) })
+	register("describe", func(v interface{}) string { return fmt.Sprintf("%T", v) })
+	register("explode", func() { panic("boom") })
+	register("small", func(n int8) int8 { return n })
+	register("huge", func() uint64 { return ^uint64(0) })
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`repeat("ab", 3);`, "ababab"},
+		{`isLong("hello", 3);`, "true"},
+		{`sum();`, "0.0"},
+		{`sum(1, 2.5);`, "3.5"},
+		{`total({"a": 1, "b": 2});`, "3"},
+		{`split("a,b");`, "[a, b]"},
+		{`upper('x');`, "X"},
+		{`describe(1) + " " + describe([1, "a"]) + " " + describe({"k": 1}) + " " + describe(first([]));`,
+			"int64 []interface {} map[string]interface {} <nil>"},
+		{`repeat("ab");`, "ERROR: wrong number of arguments. got=1, want=2"},
+		{`repeat(1, 2);`, "ERROR: argument 0 to `repeat` must be STRING, got INTEGER"},
+		{`isLong("x", -1);`, "ERROR: limit must not be negative"},
+		{`total({"a": "x"});`, "ERROR: argument 0 to `total` at key a must be INTEGER, got STRING"},
+		{`explode();`, "ERROR: panic in Go function `explode`: boom"},
+		{`small(300);`, "ERROR: argument 0 to `small` must fit in int8, got 300"},
+		{`huge();`, "ERROR: result of Go function `huge`: 18446744073709551615 overflows INTEGER"},
+		{`repeat = 1;`, "ERROR: assignment to constant variable: repeat"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Run(context.Background(), tt.input, "register.gct")
+		var got string
+		var runtimeErr *RuntimeError
+		switch {
+		case errors.As(err, &runtimeErr):
+			got = runtimeErr.Err.Inspect()
+		case err != nil:
+			t.Fatalf("%s: unexpected error %v", tt.input, err)
+		default:
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// tag is a script value defined by the host that is not a pointer. Every tag
+// has the same hash key, so tags used as keys of a map share a bucket.
+type tag struct{ name string }
+
+func (t tag) Type() object.ObjectType { return "TAG" }
+func (t tag) Inspect() string         { return "#" + t.name }
+func (t tag) HashKey() object.HashKey { return object.HashKey{Type: "TAG"} }
+
+func TestRegisterValue(t *testing.T) {
+	interp := New()
+
+	values := map[string]interface{}{
+		"limits": map[string][]int{"b": {3, 4}, "a": {1, 2}},
+		"name":   "goception",
+		"ratio":  float32(0.5),
+		"none":   (*int)(nil),
+		"tags":   []tag{{"a"}, {"b"}},
+	}
+	for name, value := range values {
+		if err := interp.Register(name, value); err != nil {
+			t.Fatalf("register %s failed: %v", name, err)
+		}
+	}
+
+	result, err := interp.Run(context.Background(), `[limits, name, ratio, none, tags];`, "values.gct")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	expected := "[{a: [1, 2], b: [3, 4]}, goception, 0.5, null, [#a, #b]]"
+	if result.Inspect() != expected {
+		t.Errorf("expected=%q, got=%q", expected, result.Inspect())
+	}
+
+	// Keys with the same hash key are told apart
+	result, err = interp.Run(context.Background(), `var h = {}; h[tags[0]] = 1; h[tags[1]] = 2; h[tags[0]] = 3; h;`, "tags.gct")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result.Inspect() != "{#a: 3, #b: 2}" {
+		t.Errorf("expected=%q, got=%q", "{#a: 3, #b: 2}", result.Inspect())
+	}
+
+	// A script may shadow a registered name with its own binding
+	result, err = interp.Run(context.Background(), `var name = "shadowed"; name;`, "shadow.gct")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result.Inspect() != "shadowed" {
+		t.Errorf("expected the script binding, got %s", result.Inspect())
+	}
+
+	if err := interp.Register("bad", func() (int, int) { return 0, 0 }); err == nil {
+		t.Errorf("expected an error registering a function with two results")
+	}
+	if err := interp.Register("bad", make(chan int)); err == nil {
+		t.Errorf("expected an error registering a channel")
+	}
+}
+
+func TestScriptCallbacks(t *testing.T) {
+	interp := New()
+
+	var handlers []*Function
+	if err := interp.Register("onEvent", func(handler *Function) { handlers = append(handlers, handler) }); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	source := `
+		var count = 0;
+		onEvent(function(e: string): string {
+			count = count + 1;
+			return e + " #" + count;
+		});
+		onEvent(len);
+	`
+	if _, err := interp.Run(context.Background(), source, "callbacks.gct"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(handlers) != 2 {
+		t.Fatalf("expected 2 handlers, got %d", len(handlers))
+	}
+
+	// The closure keeps its environment between calls made after Run returned
+	for i, expected := range []string{"click #1", "click #2"} {
+		result, err := handlers[0].Call("click")
+		if err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+		var got string
+		if err := interp.Decode(result, &got); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		if got != expected {
+			t.Errorf("call %d: expected=%q, got=%q", i, expected, got)
+		}
+	}
+
+	_, err := handlers[0].Call(42)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Message != "type mismatch for argument 0: expected string, got INTEGER" {
+		t.Errorf("expected an argument type mismatch, got %v", err)
+	}
+
+	result, err := handlers[1].Call("héllo")
+	if err != nil {
+		t.Fatalf("calling a builtin failed: %v", err)
+	}
+	testInteger(t, result, 5)
+
+	if err := interp.Register("notify", func(handler *Function) {}); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	_, err = interp.Run(context.Background(), `notify(1);`, "notify.gct")
+	if err == nil || !strings.Contains(err.Error(), "argument 0 to `notify` must be FUNCTION, got INTEGER") {
+		t.Errorf("expected a FUNCTION argument error, got %v", err)
+	}
+}
+
+func TestFunctionLookup(t *testing.T) {
+	interp := New()
+	source := `const scale = function(values: array, factor: int): array {
+		var scaled = [];
+		for (var i = 0; i < len(values); i = i + 1) {
+			push(scaled, values[i] * factor);
+		}
+		return scaled;
+	};
+	const answer = 42;`
+	if _, err := interp.Run(context.Background(), source, "scale.gct"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	scale, err := interp.Function("scale")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	result, err := scale.Call([]int{1, 2, 3}, 10)
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+
+	var scaled []int
+	if err := interp.Decode(result, &scaled); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if fmt.Sprint(scaled) != "[10 20 30]" {
+		t.Errorf("wrong result: %v", scaled)
+	}
+
+	var names []string
+	if err := interp.Decode(result, &names); err == nil || err.Error() != "goception: value at index 0 must be STRING, got INTEGER" {
+		t.Errorf("expected a decode error, got %v", err)
+	}
+
+	if _, err := interp.Function("answer"); err == nil {
+		t.Errorf("expected an error looking up a non-function")
+	}
+	if _, err := interp.Function("missing"); err == nil {
+		t.Errorf("expected an error looking up an undefined name")
+	}
+}
+
+func TestConcurrentInterpreters(t *testing.T) {
+	// Each interpreter imports the same module, so they would race on shared
+	// import state if there were any
+	module := filepath.Join(t.TempDir(), "counter.gct")
+	source := `export const next = function(n: int): int { return n + 1; };`
+	if err := os.WriteFile(module, []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			interp := New()
+			script := fmt.Sprintf(`
+				import %q;
+				var total = %d;
+				for (var i = 0; i < 100; i = i + 1) {
+					total = next(total);
+				}
+				const seen = {"worker": %d, "flags": [true, false]};
+				total;
+			`, module, w, w)
+
+			result, err := interp.Run(context.Background(), script, fmt.Sprintf("worker-%d.gct", w))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if result.Inspect() != fmt.Sprint(w+100) {
+				errs <- fmt.Errorf("worker %d: expected %d, got %s", w, w+100, result.Inspect())
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		input    string
+		expected string
+	}{
+		{
+			"DefaultCallDepth",
+			nil,
+			`const f = function() { return f(); }; f();`,
+			"call depth limit of 10000 exceeded",
+		},
+		{
+			"CallDepth",
+			[]Option{WithMaxCallDepth(10)},
+			`const f = function(n) { if (n == 0) { return 0; } return f(n - 1); }; f(10);`,
+			"call depth limit of 10 exceeded",
+		},
+		{
+			"Steps",
+			[]Option{WithMaxSteps(1000)},
+			`while (true) {}`,
+			"step limit of 1000 exceeded",
+		},
+		{
+			"StringAllocation",
+			[]Option{WithMaxAllocation(1024)},
+			`var s = "x"; while (true) { s = s + s; }`,
+			"allocation limit of 1024 exceeded",
+		},
+		{
+			"ArrayAllocation",
+			[]Option{WithMaxAllocation(100)},
+			`var a = []; while (true) { push(a, 1); }`,
+			"allocation limit of 100 exceeded",
+		},
+		{
+			"HashAllocation",
+			[]Option{WithMaxAllocation(100)},
+			`var h = {}; var i = 0; while (true) { h[i] = i; i = i + 1; }`,
+			"allocation limit of 100 exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.options...).Run(context.Background(), tt.input, "limits.gct")
+
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected *LimitError, got %T (%v)", err, err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("expected=%q, got=%q", tt.expected, err.Error())
+			}
+		})
+	}
+
+	// A script within its limits runs normally, and every Run gets a fresh step budget
+	interp := New(WithMaxCallDepth(10), WithMaxSteps(1000))
+	source := `const f = function(n) { if (n == 0) { return 0; } return f(n - 1); }; f(9);`
+	for i := 0; i < 3; i++ {
+		if _, err := interp.Run(context.Background(), source, "within.gct"); err != nil {
+			t.Fatalf("run %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := New().Run(ctx, `while (true) {}`, "forever.gct")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// A callback is bounded by its own context
+	interp := New()
+	if _, err := interp.Run(context.Background(), `const spin = function() { while (true) {} };`, "spin.gct"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	spin, err := interp.Function("spin")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := spin.CallContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	for _, input := range []string{`1 / 0;`, `1 % 0;`} {
+		_, err := New().Run(context.Background(), input, "zero.gct")
+		if err == nil || err.Error() != "zero.gct:1:3: division by zero" {
+			t.Errorf("%s: expected a division by zero error, got %v", input, err)
+		}
+	}
+}
+
+func TestEmptyBlocks(t *testing.T) {
+	// An empty block or function body evaluates to null
+	tests := []string{
+		`var r = function() {}(); r;`,
+		`var r = if (true) {}; r;`,
+		`const f = function(): int? {}; f();`,
+		`var x = 1; x = if (false) {} else {}; x;`,
+	}
+
+	for _, input := range tests {
+		result, err := New().Run(context.Background(), input, "empty.gct")
+		if err != nil {
+			t.Errorf("%s: run failed: %v", input, err)
+			continue
+		}
+		if result.Type() != object.NULL_OBJ {
+			t.Errorf("%s: expected null, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestAssignmentTypes(t *testing.T) {
+	// The values are only known at runtime, so the checker accepts them
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var total = 0;\nconst set = function(v) { total = v; };\nset(\"oops\");",
+			"assign.gct:2:33: type mismatch in assignment to total: expected int?, got STRING"},
+		{"var name: string = \"Ada\";\nconst set = function(v) { name = v; };\nset(1);",
+			"assign.gct:2:32: type mismatch in assignment to name: expected string, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		_, err := New().Run(context.Background(), tt.input, "assign.gct")
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("%s: wrong error. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	// Null and values of the declared type may be assigned
+	source := "var total = 0; const set = function(v) { total = v; }; set(2); set(first([]));"
+	if _, err := New().Run(context.Background(), source, "assign.gct"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFunctionTypes(t *testing.T) {
+	interp := New()
+	if err := interp.Register("repeat", func(s string, n int) string { return strings.Repeat(s, n) }); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if err := interp.Register("sum", func(values ...int) int { return len(values) }); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	setup := `
+		const twice = function(f: (string, int) -> string, s: string): string { return f(s, 2); };
+		const call = function(f: (int) -> int) { return f(1); };
+		const pick = function(fs, i) { return fs[i]; };
+	`
+	if _, err := interp.Run(context.Background(), setup, "setup.gct"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Registered Go functions declare their signature
+		{`twice(repeat, "ab");`, "abab"},
+		{`call(repeat);`, "ERROR: type mismatch for argument 0: expected (int) -> int, got BUILTIN"},
+		// Builtins without a declared signature are accepted
+		{`call(sum);`, "1"},
+		{`call(len);`, "ERROR: argument to `len` not supported, got INTEGER"},
+		// Script functions are checked against their annotations
+		{`call(pick([function(n: int): int { return n + 1; }], 0));`, "2"},
+		{`call(pick([function(n: int): string { return "n"; }], 0));`,
+			"ERROR: type mismatch for argument 0: expected (int) -> int, got FUNCTION"},
+		{`call(pick([function(a: int, b: int): int { return a; }], 0));`,
+			"ERROR: type mismatch for argument 0: expected (int) -> int, got FUNCTION"},
+		{`call(pick([function(n) { return n * 3; }], 0));`, "3"},
+		// Every function takes exactly one argument for each parameter
+		{`pick([function(a, b) { return a; }], 0)(1);`, "ERROR: wrong number of arguments: expected 2, got 1"},
+		{`pick([function(a) { return a; }], 0)(1, 2);`, "ERROR: wrong number of arguments: expected 1, got 2"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Run(context.Background(), tt.input, "types.gct")
+		var got string
+		var runtimeErr *RuntimeError
+		switch {
+		case errors.As(err, &runtimeErr):
+			got = runtimeErr.Err.Inspect()
+		case err != nil:
+			t.Fatalf("%s: unexpected error %v", tt.input, err)
+		default:
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestNullableTypes(t *testing.T) {
+	// The values pass through an unannotated function, so only the runtime sees them
+	setup := "const id = function(v) { return v; };\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var n: int? = id(null); n = id(5); n;`, "5"},
+		{`var u: int | string = id("a"); u = id(1); u;`, "1"},
+		{`const f = function(s: string?): string? { return s; }; f(id(null));`, "null"},
+		{`null == id(null);`, "true"},
+		{`var n: int = id(null);`, "ERROR: type mismatch: expected int, got NULL"},
+		{`var u: int | string = id(1); u = id(true);`,
+			"ERROR: type mismatch in assignment to u: expected int | string, got BOOLEAN"},
+		{`const f = function(n): int { return n; }; f(id(null));`,
+			"ERROR: return type mismatch: expected int, got NULL"},
+		{`const apply = function(f: (int?) -> int) { return f(null); }; apply(id(function(n: int): int { return n; }));`,
+			"ERROR: type mismatch for argument 0: expected (int?) -> int, got FUNCTION"},
+		// Variables without an annotation may also be null
+		{`var count = 0; count = id(null); count;`, "null"},
+	}
+
+	for _, tt := range tests {
+		result, err := New().Run(context.Background(), setup+tt.input, "nullable.gct")
+		var got string
+		var runtimeErr *RuntimeError
+		switch {
+		case errors.As(err, &runtimeErr):
+			got = runtimeErr.Err.Inspect()
+		case err != nil:
+			t.Fatalf("%s: unexpected error %v", tt.input, err)
+		default:
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCollectionTypes(t *testing.T) {
+	interp := New()
+	if err := interp.Register("total", func(values []int) int { return len(values) }); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	// The values pass through an unannotated function, so only the runtime sees them
+	setup := "const id = function(v) { return v; };\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var xs: array<int> = id([1, 2]); xs = id([]); len(xs);`, "0"},
+		{`var m: map<string, array<bool>> = id({"a": [true]}); m["a"];`, "[true]"},
+		{`var ys: array<int?> = id([1, null]); ys;`, "[1, null]"},
+		{`var xs: array<int> = id([1, "two", 3]);`,
+			"ERROR: type mismatch: expected array<int>, got ARRAY (at index 1: expected int, got STRING)"},
+		{`var m: map<string, array<bool>> = id({"a": [true]}); m = id({"a": [true], "b": [false, 1]});`,
+			"ERROR: type mismatch in assignment to m: expected map<string, array<bool>>, got HASH (at key b, index 1: expected bool, got INTEGER)"},
+		{`const count = function(m: map<string, int>): int { return len(m); }; count(id({1: 2}));`,
+			"ERROR: type mismatch for argument 0: expected map<string, int>, got HASH (at key 1: expected string, got INTEGER)"},
+		{`const names = function(): array<string> { return id(["a", 'b']); }; names();`,
+			"ERROR: return type mismatch: expected array<string>, got ARRAY (at index 1: expected string, got CHAR)"},
+		// Elements changed through a variable declared with their types must have them
+		{`var arr: array<int> = id([1]); arr[0] = id(2); push(arr, id(3)); arr;`, "[2, 3]"},
+		{`var arr: array<int> = id([1]); arr[0] = id("x");`,
+			"ERROR: type mismatch in assignment to arr[0]: expected int, got STRING"},
+		{`var arr: array<int> = id([1]); push(arr, 2, id("x"));`,
+			"ERROR: type mismatch for argument 2: expected int, got STRING"},
+		{`var m: map<string, int> = id({}); m["b"] = id("no");`,
+			"ERROR: type mismatch in assignment to m[\"b\"]: expected int, got STRING"},
+		{`var m: map<string, int> = id({}); m[id(1)] = 2;`,
+			"ERROR: type mismatch for key in assignment to m[id(1)]: expected string, got INTEGER"},
+		{`var arr: array = id([1]); arr[0] = "x"; push(arr, true); arr;`, "[x, true]"},
+		// The element types belong to the variable, so changes made through another one are not checked
+		{`var arr: array<int> = id([1]); var other = arr; other[0] = "s"; push(other, true); arr;`, "[s, true]"},
+		{`var arr: array<int> = id([1]); const set = function(xs) { xs[0] = "s"; }; set(arr); arr;`, "[s]"},
+		// Registered Go functions declare the element types of their parameters
+		{`const apply = function(f: (array<int>) -> int) { return f([1, 2]); }; apply(total);`, "2"},
+		{`const apply = function(f: (array<string>) -> int) { return f(["a"]); }; apply(id(total));`,
+			"ERROR: type mismatch for argument 0: expected (array<string>) -> int, got BUILTIN"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Run(context.Background(), setup+tt.input, "collections.gct")
+		var got string
+		var runtimeErr *RuntimeError
+		switch {
+		case errors.As(err, &runtimeErr):
+			got = runtimeErr.Err.Inspect()
+		case err != nil:
+			t.Fatalf("%s: unexpected error %v", tt.input, err)
+		default:
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStreams(t *testing.T) {
+	module := filepath.Join(t.TempDir(), "greeting.gct")
+	if err := os.WriteFile(module, []byte(`export const greeting = "Hello";`), 0644); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+
+	var stdout, stderr, debug strings.Builder
+	interp := New(
+		WithStdout(&stdout),
+		WithStderr(&stderr),
+		WithStdin(strings.NewReader("Ada\r\nGrace")),
+		WithDebugLogger(log.New(&debug, "", 0)),
+	)
+
+	source := fmt.Sprintf(`
+		import %q;
+		var names = [];
+		var name = input("Name? ");
+		while (len(name) > 0) {
+			push(names, name);
+			name = input();
+			if (name == first([])) { break; }
+		}
+		print(greeting + " " + names[0], greeting + " " + names[1]);
+		eprint("done");
+	`, module)
+	if _, err := interp.Run(context.Background(), source, "streams.gct"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if expected := "Name? Hello Ada\nHello Grace\n"; stdout.String() != expected {
+		t.Errorf("wrong stdout. expected=%q, got=%q", expected, stdout.String())
+	}
+	if expected := "done\n"; stderr.String() != expected {
+		t.Errorf("wrong stderr. expected=%q, got=%q", expected, stderr.String())
+	}
+	if !strings.Contains(debug.String(), "Successfully imported: "+module) {
+		t.Errorf("expected import diagnostics in the debug log, got %q", debug.String())
+	}
+}
+
+func TestImportResolvers(t *testing.T) {
+	modules := MapResolver{
+		"main.gct":         `import "util/strings.gct"; import "/shared.gct"; shout("hi") + suffix;`,
+		"util/strings.gct": `import "helpers.gct"; export const shout = function(s) { return twice(s) + "!"; };`,
+		"util/helpers.gct": `export const twice = function(s) { return s + s; };`,
+		"shared.gct":       `export const suffix = "?";`,
+	}
+
+	// On disk, shared.gct lives in a separate directory on the search path
+	dir, lib := t.TempDir(), t.TempDir()
+	for name, source := range modules {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if name == "shared.gct" {
+			target = filepath.Join(lib, name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(target, []byte(source), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	files := fstest.MapFS{}
+	for name, source := range modules {
+		files[name] = &fstest.MapFile{Data: []byte(source)}
+	}
+
+	tests := []struct {
+		name     string
+		resolver ImportResolver
+		filename string
+		source   string
+	}{
+		{"file", &FileResolver{SearchPath: []string{lib}}, filepath.Join(dir, "main.gct"),
+			strings.Replace(modules["main.gct"], "/shared.gct", "shared.gct", 1)},
+		{"fs", &FSResolver{FS: files}, "main.gct", modules["main.gct"]},
+		{"map", modules, "main.gct", modules["main.gct"]},
+	}
+
+	for _, tt := range tests {
+		result, err := New(WithImportResolver(tt.resolver)).Run(context.Background(), tt.source, tt.filename)
+		if err != nil {
+			t.Errorf("%s: run failed: %v", tt.name, err)
+			continue
+		}
+		if result.Inspect() != "hihi!?" {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.name, "hihi!?", result.Inspect())
+		}
+	}
+}
+
+func TestImportNotFound(t *testing.T) {
+	interp := New(WithImportResolver(MapResolver{}))
+	_, err := interp.Run(context.Background(), `import "lib/missing.gct";`, "app/main.gct")
+	expected := "app/main.gct:1:1: could not import lib/missing.gct: file does not exist (tried app/lib/missing.gct)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%v", expected, err)
+	}
+
+	_, err = interp.Run(context.Background(), `import "../../secret.gct";`, "main.gct")
+	if err == nil || !strings.Contains(err.Error(), "invalid import path: ../../secret.gct") {
+		t.Errorf("expected an invalid import path error, got %v", err)
+	}
+}
+
+func TestNamespacedImports(t *testing.T) {
+	modules := MapResolver{
+		"shapes.gct": `
+			const sides = function(shape) { return len(shape); };
+			export const PI = 3;
+			export const square = function(x) { return x * x; };
+			export const perimeter = function(x) { return sides("four") * x; };
+		`,
+		"numbers.gct": `export const square = function(x) { return x * x * 10; };`,
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "shapes" as s; import "numbers" as n; s.square(2) + n.square(2);`, "44"},
+		{`import { square, PI } from "shapes"; square(PI);`, "9"},
+		{`import "shapes" as s; s;`, "module shapes.gct"},
+		{`import { perimeter } from "shapes"; perimeter(2);`, "8"},
+		{`import "shapes"; var sides = 1; perimeter(sides);`, "4"},
+	}
+
+	for _, tt := range tests {
+		result, err := New(WithImportResolver(modules)).Run(context.Background(), tt.input, "main.gct")
+		if err != nil {
+			t.Errorf("%s: run failed: %v", tt.input, err)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`import { square } from "shapes"; import { square } from "numbers";`,
+			"main.gct:1:34: cannot import square from numbers.gct: square is already defined"},
+		{`import "shapes"; import "numbers";`,
+			"main.gct:1:18: cannot import square from numbers.gct: square is already defined"},
+		{`const PI = 1; import "shapes"; print(PI);`,
+			"main.gct:1:15: cannot import PI from shapes.gct: PI is already defined"},
+		{`var s = 1; import "shapes" as s;`,
+			"main.gct:1:12: cannot import shapes.gct as s: s is already defined"},
+		{`import { cube } from "shapes";`,
+			"main.gct:1:1: cannot import cube from shapes.gct: it is not defined there"},
+		{`import "shapes" as s; s.cube(2);`,
+			"main.gct:1:24: module shapes.gct does not export cube"},
+		{`import { sides } from "shapes";`,
+			"main.gct:1:1: cannot import sides from shapes.gct: it is not exported"},
+		{`import "shapes" as s; s.sides("four");`,
+			"main.gct:1:24: module shapes.gct does not export sides"},
+		{`import "shapes"; sides("four");`,
+			"main.gct:1:18: identifier not found: sides"},
+		{`import "shapes" as s; s = 1;`,
+			"main.gct:1:25: assignment to constant variable: s"},
+	}
+
+	for _, tt := range errorTests {
+		_, err := New(WithImportResolver(modules)).Run(context.Background(), tt.input, "main.gct")
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%s: wrong error. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestModulesEvaluatedOnce(t *testing.T) {
+	modules := MapResolver{
+		"counter.gct": `print("loading counter"); export const counter = {"count": 0};`,
+		"left.gct":    `import { counter } from "counter"; counter["count"] = counter["count"] + 1; export const left = 1;`,
+		"right.gct":   `import "counter" as c; c.counter["count"] = c.counter["count"] + 1; export const right = 2;`,
+	}
+
+	var stdout strings.Builder
+	interp := New(WithImportResolver(modules), WithStdout(&stdout))
+	source := `import "left"; import "right"; import "counter"; counter["count"] + left + right;`
+	result, err := interp.Run(context.Background(), source, "main.gct")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result.Inspect() != "5" {
+		t.Errorf("expected both modules to share one counter, got %s", result.Inspect())
+	}
+
+	// Later runs on the same interpreter reuse the module too
+	if _, err := interp.Run(context.Background(), `import "counter" as again;`, "main.gct"); err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	if stdout.String() != "loading counter\n" {
+		t.Errorf("expected counter.gct to be evaluated once, got output %q", stdout.String())
+	}
+}
+
+func TestImportCycles(t *testing.T) {
+	modules := MapResolver{
+		"a.gct":        `import "b"; export const a = 1;`,
+		"b.gct":        `import { a } from "a"; export const b = a;`,
+		"self.gct":     `import "self";`,
+		"main.gct":     `import "lib/main";`,
+		"lib/main.gct": `import "/main";`,
+	}
+
+	tests := []struct {
+		input    string
+		filename string
+		expected string
+	}{
+		{`import "a";`, "main.gct", "b.gct:1:1: import cycle: a.gct → b.gct → a.gct"},
+		{`import "b";`, "a.gct", "b.gct:1:1: import cycle: a.gct → b.gct → a.gct"},
+		{`import "self";`, "main.gct", "self.gct:1:1: import cycle: self.gct → self.gct"},
+		{modules["main.gct"], "main.gct", "lib/main.gct:1:1: import cycle: main.gct → lib/main.gct → main.gct"},
+		// The entry file is named like the files it imports
+		{modules["a.gct"], "./a.gct", "b.gct:1:1: import cycle: a.gct → b.gct → a.gct"},
+	}
+
+	for _, tt := range tests {
+		_, err := New(WithImportResolver(modules)).Run(context.Background(), tt.input, tt.filename)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%s: wrong error. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	// The same holds on the file system
+	dir := t.TempDir()
+	for _, name := range []string{"a.gct", "b.gct"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(modules[name]), 0644); err != nil {
+			t.Fatalf("failed to write module: %v", err)
+		}
+	}
+	entry := dir + string(filepath.Separator) + "." + string(filepath.Separator) + "a.gct"
+	_, err := New().Run(context.Background(), modules["a.gct"], entry)
+	expected := fmt.Sprintf("%s:1:1: import cycle: %s → %s → %s",
+		filepath.Join(dir, "b.gct"), filepath.Join(dir, "a.gct"), filepath.Join(dir, "b.gct"), filepath.Join(dir, "a.gct"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%v", expected, err)
+	}
+}
+
+func TestTypeCheckImports(t *testing.T) {
+	modules := MapResolver{
+		"main.gct": `import { f } from "lib/util"; f();`,
+		"lib/util.gct": `import "helpers";
+			export const f = function() { if (false) { const n: int = "x"; } return 1; };`,
+		"lib/helpers.gct": `export const h = 1;`,
+		"lib/broken.gct":  `const s: string = 1;`,
+	}
+	interp := New(WithImportResolver(modules))
+
+	// A type error in a file imported by an import is found without running any file
+	err := interp.Check(modules["main.gct"], "main.gct")
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected a *TypeError, got %T: %v", err, err)
+	}
+	expected := "lib/util.gct: line 2, column 62: type mismatch: expected int, got STRING"
+	if err.Error() != expected || typeErr.Source != modules["lib/util.gct"] {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, err.Error())
+	}
+
+	if err := interp.Check(`import "lib/missing";`, "main.gct"); err == nil ||
+		err.Error() != "main.gct: could not import lib/missing.gct: file does not exist (tried lib/missing.gct)" {
+		t.Errorf("wrong error for a missing import: %v", err)
+	}
+
+	// An imported file is checked before it runs
+	_, err = interp.Run(context.Background(), `import "lib/broken";`, "main.gct")
+	expected = "main.gct:1:1: type errors in imported file lib/broken.gct:\n\tline 1, column 19: type mismatch: expected string, got INTEGER\n"
+	if err == nil || err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%v", expected, err)
+	}
+}
+
+func TestTypeCheckBeforeRun(t *testing.T) {
+	var stdout strings.Builder
+	interp := New(WithStdout(&stdout))
+
+	source := "print(\"never\");\nif (false) { const n: int = \"oops\"; }"
+	_, err := interp.Run(context.Background(), source, "typed.gct")
+
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected a *TypeError, got %T: %v", err, err)
+	}
+	if expected := "typed.gct: line 2, column 29: type mismatch: expected int, got STRING"; err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, err.Error())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected nothing to run, got output %q", stdout.String())
+	}
+
+	if err := Check(source, "typed.gct"); !errors.As(err, &typeErr) {
+		t.Errorf("expected Check to return a *TypeError, got %v", err)
+	}
+	if err := Check(`print(`, "broken.gct"); !errors.As(err, new(*ParseError)) {
+		t.Errorf("expected Check to return a *ParseError, got %v", err)
+	}
+	if err := Check(`const n: int = 1;`, "valid.gct"); err != nil {
+		t.Errorf("expected a valid script to pass, got %v", err)
+	}
+
+	// Registered values and earlier globals are not known to the checker
+	if err := interp.Register("len", func(s string) string { return s + s }); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if _, err := interp.Run(context.Background(), `const twice: string = len("ab");`, "host.gct"); err != nil {
+		t.Errorf("expected a registered function to hide the builtin, got %v", err)
+	}
+}
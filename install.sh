@@ -15,7 +15,7 @@ fi
 
 # Build the binary
 echo "Building Goception..."
-go build -o goception main.go
+go build -o goception ./cmd/goception
 if [ $? -ne 0 ]; then
     echo "Error: Build failed."
     exit 1
@@ -1,17 +1,21 @@
 package lexer
 
 import (
+	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/onurravli/goception/token"
 )
 
-// Lexer takes input and tokenizes it
+// Lexer takes input and tokenizes it. The input is processed as a sequence of
+// runes, so positions and columns count Unicode characters rather than bytes.
 type Lexer struct {
-	input        string
+	input        []rune
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	ch           rune // current char under examination
 	line         int  // current line number
 	column       int  // current column number
 }
@@ -19,7 +23,7 @@ type Lexer struct {
 // New creates a new Lexer
 func New(input string) *Lexer {
 	l := &Lexer{
-		input:  input,
+		input:  []rune(input),
 		line:   1,
 		column: 0,
 	}
@@ -29,8 +33,13 @@ func New(input string) *Lexer {
 
 // readChar reads the next character and advances our position in the input string
 func (l *Lexer) readChar() {
+	// Already past the end of input; stay put so the EOF position is stable
+	if l.readPosition > len(l.input) {
+		return
+	}
+
 	if l.readPosition >= len(l.input) {
-		l.ch = 0 // ASCII code for "NUL" char
+		l.ch = 0 // NUL marks the end of input
 	} else {
 		l.ch = l.input[l.readPosition]
 	}
@@ -48,11 +57,16 @@ func (l *Lexer) readChar() {
 }
 
 // peekChar returns the next character without advancing position
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+func (l *Lexer) peekChar() rune {
+	return l.peekCharAt(0)
+}
+
+// peekCharAt returns the character offset positions after the next one without advancing position
+func (l *Lexer) peekCharAt(offset int) rune {
+	if l.readPosition+offset >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition]
+	return l.input[l.readPosition+offset]
 }
 
 // NextToken returns the next token from the input
@@ -61,8 +75,9 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespace()
 
-	tok.Line = l.line
-	tok.Column = l.column
+	line, column := l.line, l.column
+	tok.Line = line
+	tok.Column = column
 
 	switch l.ch {
 	case '=':
@@ -77,7 +92,14 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.ARROW, Literal: literal}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -139,6 +161,26 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.GT, l.ch)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.AND, Literal: literal}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.OR, Literal: literal}
+		} else {
+			tok = newToken(token.PIPE, l.ch)
+		}
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ':':
@@ -157,9 +199,32 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
+	case '.':
+		if isDigit(l.peekChar()) {
+			tok.Type, tok.Literal = l.readNumber()
+			return tok
+		}
+		tok = newToken(token.DOT, l.ch)
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		str, err := l.readString(tok.Line, tok.Column)
+		if err != nil {
+			tok.Type = token.ERROR
+			tok.Literal = err.message
+			line, column = err.line, err.column
+		} else {
+			tok.Type = token.STRING
+			tok.Literal = str
+		}
+	case '\'':
+		ch, err := l.readCharLiteral(tok.Line, tok.Column)
+		if err != nil {
+			tok.Type = token.ERROR
+			tok.Literal = err.message
+			line, column = err.line, err.column
+		} else {
+			tok.Type = token.CHAR
+			tok.Literal = string(ch)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -169,43 +234,182 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 
+	// Tokens built with newToken or a composite literal above lose their position.
+	// Error tokens point at the problem itself, e.g. a bad escape inside a string.
+	tok.Line = line
+	tok.Column = column
+
 	l.readChar()
 	return tok
 }
 
-// readString reads a string enclosed in double quotes
-func (l *Lexer) readString() string {
+// literalError describes a malformed string or char literal and where the problem is
+type literalError struct {
+	line    int
+	column  int
+	message string
+}
+
+// readString reads a string enclosed in double quotes, resolving escape sequences.
+// line and column give the position of the opening quote for error reporting. On a
+// bad escape the rest of the string is still consumed so lexing can resume after it.
+func (l *Lexer) readString(line, column int) (string, *literalError) {
 	// Skip the opening quote
 	l.readChar()
 
-	// Read the string content, handling escape sequences if needed
 	var result strings.Builder
+	var firstErr *literalError
 
 	for {
 		if l.ch == 0 {
 			// End of file before closing quote
-			break
+			return "", &literalError{line, column, "unterminated string"}
 		}
 
 		if l.ch == '"' {
 			break
 		}
 
+		if l.ch == '\\' {
+			ch, err := l.readEscape()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			result.WriteRune(ch)
+			continue
+		}
+
 		// Add the character to the result
-		result.WriteByte(l.ch)
+		result.WriteRune(l.ch)
+		l.readChar()
+	}
+
+	if firstErr != nil {
+		return "", firstErr
+	}
+
+	return result.String(), nil
+}
+
+// readCharLiteral reads a character enclosed in single quotes, resolving an escape
+// sequence if there is one. line and column give the position of the opening quote.
+// A literal that is empty, holds more than one character or is not terminated is an error.
+func (l *Lexer) readCharLiteral(line, column int) (rune, *literalError) {
+	// Skip the opening quote
+	l.readChar()
+
+	var ch rune
+	switch l.ch {
+	case '\'':
+		return 0, &literalError{line, column, "empty char literal"}
+	case '\n', 0:
+		return 0, &literalError{line, column, "unterminated char literal"}
+	case '\\':
+		escaped, err := l.readEscape()
+		if err != nil {
+			l.skipCharLiteral()
+			return 0, err
+		}
+		ch = escaped
+	default:
+		ch = l.ch
 		l.readChar()
 	}
 
-	// Return the full string
-	return result.String()
+	if l.ch != '\'' {
+		l.skipCharLiteral()
+		if l.ch != '\'' {
+			return 0, &literalError{line, column, "unterminated char literal"}
+		}
+		return 0, &literalError{line, column, "char literal holds more than one character"}
+	}
+
+	return ch, nil
+}
+
+// skipCharLiteral advances to the closing quote of a malformed char literal, or to
+// the end of the line if there is none, so lexing can resume after it
+func (l *Lexer) skipCharLiteral() {
+	for l.ch != '\'' && l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// simpleEscapes maps the character following a backslash to the character it denotes
+var simpleEscapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+}
+
+// readEscape reads an escape sequence starting at the current backslash and returns
+// the character it denotes, leaving the lexer on the first character after it.
+// Supported forms are the simple escapes, \xNN and \u{N...} with up to six hex digits.
+func (l *Lexer) readEscape() (rune, *literalError) {
+	line, column := l.line, l.column
+
+	l.readChar() // Skip the backslash
+	esc := l.ch
+
+	if ch, ok := simpleEscapes[esc]; ok {
+		l.readChar()
+		return ch, nil
+	}
+
+	switch esc {
+	case 'x':
+		l.readChar() // Skip the 'x'
+		var value rune
+		for i := 0; i < 2; i++ {
+			digit, ok := hexValue(l.ch)
+			if !ok {
+				return utf8.RuneError, &literalError{line, column, "invalid escape sequence: \\x must be followed by two hex digits"}
+			}
+			value = value*16 + digit
+			l.readChar()
+		}
+		return value, nil
+	case 'u':
+		l.readChar() // Skip the 'u'
+		if l.ch != '{' {
+			return utf8.RuneError, &literalError{line, column, "invalid escape sequence: expected { after \\u"}
+		}
+		l.readChar() // Skip the '{'
+
+		var value rune
+		digits := 0
+		for l.ch != '}' {
+			digit, ok := hexValue(l.ch)
+			if !ok || digits == 6 {
+				return utf8.RuneError, &literalError{line, column, "invalid escape sequence: \\u{...} must hold one to six hex digits"}
+			}
+			value = value*16 + digit
+			digits++
+			l.readChar()
+		}
+		l.readChar() // Skip the '}'
+
+		if digits == 0 || !utf8.ValidRune(value) {
+			return utf8.RuneError, &literalError{line, column, "invalid escape sequence: \\u{...} is not a valid Unicode code point"}
+		}
+		return value, nil
+	case 0:
+		return utf8.RuneError, &literalError{line, column, "invalid escape sequence: unexpected end of input"}
+	default:
+		l.readChar()
+		return utf8.RuneError, &literalError{line, column, fmt.Sprintf("invalid escape sequence \\%c", esc)}
+	}
 }
 
 // readIdentifier reads an identifier and advances the lexer's position
@@ -214,16 +418,44 @@ func (l *Lexer) readIdentifier() string {
 	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return string(l.input[position:l.position])
 }
 
-// readNumber reads a number and advances the lexer's position
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or floating-point number and advances the lexer's position.
+// A number is a float if it has a fractional part (3.14, .5) or an exponent (1e-9).
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
+	tokType := token.TokenType(token.INT)
+
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+
+	// Fractional part
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokType = token.FLOAT
+		l.readChar() // Skip the '.'
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
+	// Exponent, only consumed if it is well-formed (e.g. 1e9, 1e-9, 1E+9)
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && isDigit(l.peekCharAt(1))) {
+			tokType = token.FLOAT
+			l.readChar() // Skip the 'e'
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			for isDigit(l.ch) {
+				l.readChar()
+			}
+		}
+	}
+
+	return tokType, string(l.input[position:l.position])
 }
 
 // skipWhitespace skips any whitespace characters
@@ -234,14 +466,29 @@ func (l *Lexer) skipWhitespace() {
 }
 
 // Helper functions
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+// hexValue returns the value of a hexadecimal digit
+func hexValue(ch rune) (rune, bool) {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return ch - '0', true
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10, true
+	case 'A' <= ch && ch <= 'F':
+		return ch - 'A' + 10, true
+	default:
+		return 0, false
+	}
+}
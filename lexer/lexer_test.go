@@ -22,6 +22,10 @@ if (5 < 10) {
 }
 10 == 10;
 10 != 9;
+a && b || c;
+(int?) -> int | null;
+while for break continue
+[1, 2];
 "foobar"
 "hello world"
 // This is a comment
@@ -107,6 +111,31 @@ if (5 < 10) {
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.LPAREN, "("},
+		{token.TYPE_INT, "int"},
+		{token.QUESTION, "?"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.TYPE_INT, "int"},
+		{token.PIPE, "|"},
+		{token.NULL, "null"},
+		{token.SEMICOLON, ";"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
 		{token.STRING, "foobar"},
 		{token.STRING, "hello world"},
 		{token.EOF, ""},
@@ -128,3 +157,165 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestNumberLiterals(t *testing.T) {
+	input := `5 3.14 .5 1e-9 2E+3 10.0 7.x 4e`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, ".5"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2E+3"},
+		{token.FLOAT, "10.0"},
+		{token.INT, "7"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.INT, "4"},
+		{token.IDENT, "e"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestCharLiterals(t *testing.T) {
+	input := `'a' '\n' '\'' 'é' '\u{1F600}' '' 'ab' '\q'`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.CHAR, "a", 1},
+		{token.CHAR, "\n", 5},
+		{token.CHAR, "'", 10},
+		{token.CHAR, "é", 15},
+		{token.CHAR, "\U0001F600", 19},
+		{token.ERROR, "empty char literal", 31},
+		{token.ERROR, "char literal holds more than one character", 34},
+		{token.ERROR, "invalid escape sequence \\q", 40},
+		{token.EOF, "", 43},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d",
+				i, tt.expectedColumn, tok.Column)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	input := `"say \"hi\"\n" "tab\there" "\\" "\x41\u{e9}\0" "bad \q escape" "after"
+"unterminated`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.STRING, "say \"hi\"\n", 1, 1},
+		{token.STRING, "tab\there", 1, 16},
+		{token.STRING, "\\", 1, 28},
+		{token.STRING, "Aé\x00", 1, 33},
+		{token.ERROR, "invalid escape sequence \\q", 1, 53},
+		{token.STRING, "after", 1, 64},
+		{token.ERROR, "unterminated string", 2, 1},
+		{token.EOF, "", 2, 14},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestUnicodeInput(t *testing.T) {
+	input := `var größe = "日本語";
+größe`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.VAR, "var", 1, 1},
+		{token.IDENT, "größe", 1, 5},
+		{token.ASSIGN, "=", 1, 11},
+		{token.STRING, "日本語", 1, 13},
+		{token.SEMICOLON, ";", 1, 18},
+		{token.IDENT, "größe", 2, 1},
+		{token.EOF, "", 2, 6},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
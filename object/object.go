@@ -3,6 +3,9 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/onurravli/goception/ast"
@@ -13,13 +16,22 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
+	EXIT_OBJ         = "EXIT"
+	ABORT_OBJ        = "ABORT"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
+	CHAR_OBJ         = "CHAR"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
 	BUILTIN_OBJ      = "BUILTIN"
+	MODULE_OBJ       = "MODULE"
 )
 
 // Object represents an object in the VM
@@ -28,6 +40,17 @@ type Object interface {
 	Inspect() string
 }
 
+// HashKey identifies a hashable object as a key of a Hash
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by objects that can be used as hash keys
+type Hashable interface {
+	HashKey() HashKey
+}
+
 // Integer represents an integer
 type Integer struct {
 	Value int64
@@ -35,6 +58,31 @@ type Integer struct {
 
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// Float represents a floating-point number
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	// Keep whole floats recognisable as floats, e.g. 2.0 rather than 2
+	if !strings.ContainsAny(s, ".eEnN") {
+		s += ".0"
+	}
+	return s
+}
+func (f *Float) HashKey() HashKey {
+	// A whole float has the key of the equal integer, since 1 == 1.0
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
 
 // Boolean represents a boolean
 type Boolean struct {
@@ -43,6 +91,13 @@ type Boolean struct {
 
 func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
 func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
 
 // Null represents a null value
 type Null struct{}
@@ -58,18 +113,98 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
-// Error represents an error
+// Break signals a break statement unwinding to the innermost loop
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+// Continue signals a continue statement unwinding to the innermost loop
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+// Exit signals a call to the exit builtin unwinding to the top of the program
+type Exit struct {
+	Code int
+}
+
+func (e *Exit) Type() ObjectType { return EXIT_OBJ }
+func (e *Exit) Inspect() string  { return fmt.Sprintf("exit(%d)", e.Code) }
+
+// Abort signals that evaluation was stopped from outside the script's control,
+// because its context was cancelled or it exceeded a limit. It unwinds like an
+// error, but records no position or stack.
+type Abort struct {
+	Err error
+}
+
+func (a *Abort) Type() ObjectType { return ABORT_OBJ }
+func (a *Abort) Inspect() string  { return "ERROR: " + a.Err.Error() }
+
+// Error represents an error. File, Line and Column locate the node that
+// raised it; Line is 0 until the evaluator has located the error.
 type Error struct {
 	Message string
+	File    string
+	Line    int
+	Column  int
+	Stack   []Frame // the function calls the error unwound through, innermost first
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
+// Trace returns the error message followed by one line per location the error
+// passed through, starting where it was raised, e.g.
+//
+//	ERROR: type mismatch: INTEGER + BOOLEAN
+//	    at lib.gct:2:14 in add
+//	    at main.gct:5:1
+func (e *Error) Trace() string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Inspect())
+	if e.Line == 0 {
+		return out.String()
+	}
+
+	file, line, column := e.File, e.Line, e.Column
+	for _, frame := range e.Stack {
+		name := frame.Function
+		if name == "" {
+			name = "<anonymous>"
+		}
+		out.WriteString("\n    at " + formatPosition(file, line, column) + " in " + name)
+		file, line, column = frame.File, frame.Line, frame.Column
+	}
+	out.WriteString("\n    at " + formatPosition(file, line, column))
+
+	return out.String()
+}
+
+// Frame records a call to a script function that a runtime error unwound through
+type Frame struct {
+	Function string // the name the function was bound to, empty if anonymous
+	File     string // the file containing the call site
+	Line     int
+	Column   int
+}
+
+// formatPosition formats a source position as file:line:column
+func formatPosition(file string, line, column int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d, column %d", line, column)
+	}
+	return fmt.Sprintf("%s:%d:%d", file, line, column)
+}
+
 // Function represents a function object
 type Function struct {
+	Name       string // the name of the var or const the function was first bound to
 	Parameters []string
-	ParamTypes []string
+	ParamTypes []*ast.TypeAnnotation // nil for a parameter without an annotation
 	Body       *ast.BlockStatement
 	Env        *Environment
 	ReturnType *ast.TypeAnnotation
@@ -82,8 +217,8 @@ func (f *Function) Inspect() string {
 	params := []string{}
 	for i, p := range f.Parameters {
 		paramStr := p
-		if i < len(f.ParamTypes) && f.ParamTypes[i] != "" {
-			paramStr = p + ": " + f.ParamTypes[i]
+		if i < len(f.ParamTypes) && f.ParamTypes[i] != nil {
+			paramStr = p + ": " + f.ParamTypes[i].String()
 		}
 		params = append(params, paramStr)
 	}
@@ -95,7 +230,7 @@ func (f *Function) Inspect() string {
 
 	if f.ReturnType != nil {
 		out.WriteString(": ")
-		out.WriteString(f.ReturnType.Value)
+		out.WriteString(f.ReturnType.String())
 	}
 
 	out.WriteString(" {\n")
@@ -112,30 +247,228 @@ type String struct {
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// Char represents a single Unicode character
+type Char struct {
+	Value rune
+}
+
+func (c *Char) Type() ObjectType { return CHAR_OBJ }
+func (c *Char) Inspect() string  { return string(c.Value) }
+func (c *Char) HashKey() HashKey {
+	return HashKey{Type: c.Type(), Value: uint64(c.Value)}
+}
+
+// Array represents an ordered, mutable list of objects
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashPair is a key-value pair stored in a Hash
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash represents a mutable map from hashable keys to objects. Pairs are kept
+// in insertion order, so iteration and printing are deterministic. Keys whose
+// HashKey is the same share a bucket and are told apart by their values.
+type Hash struct {
+	buckets map[HashKey][]*HashPair
+	order   []*HashPair
+}
+
+// NewHash creates an empty hash
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]*HashPair)}
+}
+
+// find returns the bucket of key and the position of its pair in the bucket,
+// or -1 if the hash does not hold it
+func (h *Hash) find(key Hashable) (HashKey, int) {
+	hashKey := key.HashKey()
+	for i, pair := range h.buckets[hashKey] {
+		if sameKey(pair.Key, key.(Object)) {
+			return hashKey, i
+		}
+	}
+	return hashKey, -1
+}
+
+// Get returns the value stored under key
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	hashKey, i := h.find(key)
+	if i < 0 {
+		return nil, false
+	}
+	return h.buckets[hashKey][i].Value, true
+}
+
+// Set stores value under key. A new key is appended to the iteration order,
+// while an existing key keeps its position.
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey, i := h.find(key)
+	if i >= 0 {
+		h.buckets[hashKey][i].Value = value
+		return
+	}
+
+	pair := &HashPair{Key: key.(Object), Value: value}
+	h.buckets[hashKey] = append(h.buckets[hashKey], pair)
+	h.order = append(h.order, pair)
+}
+
+// Delete removes key from the hash and reports whether it was present
+func (h *Hash) Delete(key Hashable) bool {
+	hashKey, i := h.find(key)
+	if i < 0 {
+		return false
+	}
+
+	bucket := h.buckets[hashKey]
+	pair := bucket[i]
+	if len(bucket) == 1 {
+		delete(h.buckets, hashKey)
+	} else {
+		h.buckets[hashKey] = append(bucket[:i:i], bucket[i+1:]...)
+	}
+	for j, p := range h.order {
+		if p == pair {
+			h.order = append(h.order[:j], h.order[j+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Len returns the number of pairs in the hash
+func (h *Hash) Len() int { return len(h.order) }
+
+// Ordered returns the pairs of the hash in insertion order
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, 0, len(h.order))
+	for _, pair := range h.order {
+		pairs = append(pairs, *pair)
+	}
+	return pairs
+}
+
+// sameKey reports whether two hash keys are equal. An integer and a float are
+// equal when they have exactly the same value.
+func sameKey(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == b.Value
+		case *Float:
+			return sameNumber(a.Value, b.Value)
+		}
+		return false
+	case *Float:
+		switch b := b.(type) {
+		case *Integer:
+			return sameNumber(b.Value, a.Value)
+		case *Float:
+			return a.Value == b.Value
+		}
+		return false
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Char:
+		b, ok := b.(*Char)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	}
+	return a.Type() == b.Type() && a.Inspect() == b.Inspect()
+}
+
+// sameNumber reports whether a float has exactly the value of an integer
+func sameNumber(i int64, f float64) bool {
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && int64(f) == i
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Ordered() {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
 
 // BuiltinFunction represents a builtin function
 type BuiltinFunction func(args ...Object) Object
 
-// Builtin represents a builtin object
+// Builtin represents a builtin object. Its signature is declared when the
+// types of its parameters and result are known, so that it can be passed
+// where a function type is expected.
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn         BuiltinFunction
+	ParamTypes []*ast.TypeAnnotation // nil when the signature is not declared
+	ReturnType *ast.TypeAnnotation   // nil when the result has no known type
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// Module represents an imported file bound to a name - e.g., import "x" as m;
+type Module struct {
+	Name    string
+	Members map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module " + m.Name }
+
 // Environment represents a variable environment
 type Environment struct {
 	store     map[string]Object
 	outer     *Environment
-	constants map[string]bool // Track which variables are constants
+	constants map[string]bool                // Track which variables are constants
+	exported  map[string]bool                // Track which variables are visible to importers
+	types     map[string]*ast.TypeAnnotation // The declared type of each variable, if it has one
+	file      string                         // The source file evaluated in this environment, if any
 }
 
 // NewEnvironment creates a new environment
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
 	c := make(map[string]bool)
-	return &Environment{store: s, constants: c}
+	x := make(map[string]bool)
+	t := make(map[string]*ast.TypeAnnotation)
+	return &Environment{store: s, constants: c, exported: x, types: t}
 }
 
 // NewEnclosedEnvironment creates a new enclosed environment
@@ -155,20 +488,67 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
-// Set sets a variable in the environment
+// Root returns the outermost environment enclosing this one, or the
+// environment itself if it is not enclosed
+func (e *Environment) Root() *Environment {
+	if e.outer == nil {
+		return e
+	}
+	return e.outer.Root()
+}
+
+// SetFile records the source file whose code is evaluated in the environment
+func (e *Environment) SetFile(file string) {
+	e.file = file
+}
+
+// File returns the source file of the environment or of its nearest enclosing
+// environment that has one, so function bodies report the file they were defined in
+func (e *Environment) File() string {
+	if e.file == "" && e.outer != nil {
+		return e.outer.File()
+	}
+	return e.file
+}
+
+// Set sets a variable in the environment, without a declared type
 func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	e.constants[name] = false
+	delete(e.types, name)
 	return val
 }
 
-// SetConst sets a constant variable in the environment
+// SetConst sets a constant variable in the environment, without a declared type
 func (e *Environment) SetConst(name string, val Object) Object {
 	e.store[name] = val
 	e.constants[name] = true
+	delete(e.types, name)
 	return val
 }
 
+// SetType records the declared type of a variable of this environment, which
+// values later assigned to it must have
+func (e *Environment) SetType(name string, t *ast.TypeAnnotation) {
+	if t == nil {
+		delete(e.types, name)
+		return
+	}
+	e.types[name] = t
+}
+
+// TypeOf returns the declared type of a variable from the environment that
+// defines it, or nil if the variable has none
+func (e *Environment) TypeOf(name string) *ast.TypeAnnotation {
+	if _, ok := e.store[name]; ok {
+		return e.types[name]
+	}
+	if e.outer != nil {
+		return e.outer.TypeOf(name)
+	}
+	return nil
+}
+
 // Reassign reassigns a variable in the environment
 func (e *Environment) Reassign(name string, val Object) bool {
 	if _, ok := e.store[name]; ok {
@@ -191,10 +571,45 @@ func (e *Environment) Reassign(name string, val Object) bool {
 	return true
 }
 
-// ExportTo copies all variables from this environment to the target environment
+// Has reports whether a variable is defined in this environment itself,
+// ignoring enclosing environments
+func (e *Environment) Has(name string) bool {
+	_, ok := e.store[name]
+	return ok
+}
+
+// Names returns the names of the variables defined in this environment itself
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Export marks a variable of this environment as visible to importers
+func (e *Environment) Export(name string) {
+	e.exported[name] = true
+}
+
+// IsExported reports whether a variable of this environment is visible to importers
+func (e *Environment) IsExported(name string) bool {
+	return e.exported[name]
+}
+
+// Exports returns the exported variables defined in this environment itself
+func (e *Environment) Exports() map[string]Object {
+	exports := make(map[string]Object, len(e.exported))
+	for name := range e.exported {
+		exports[name] = e.store[name]
+	}
+	return exports
+}
+
+// ExportTo copies the exported variables of this environment to the target environment
 func (e *Environment) ExportTo(target *Environment) {
-	// Copy all variables from current environment to target
-	for name, val := range e.store {
+	for name := range e.exported {
+		val := e.store[name]
 		if e.constants[name] {
 			target.SetConst(name, val)
 		} else {
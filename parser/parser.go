@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"unicode/utf8"
 
 	"github.com/onurravli/goception/ast"
 	"github.com/onurravli/goception/lexer"
@@ -13,6 +14,8 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	LOGICAL_OR      // ||
+	LOGICAL_AND     // &&
 	EQUALS          // ==
 	LESSGREATER     // > or <
 	LESSTHANOREQUAL // <= or >=
@@ -20,11 +23,14 @@ const (
 	PRODUCT         // *
 	PREFIX          // -X or !X
 	CALL            // myFunction(X)
+	INDEX           // array[index]
 	ASSIGNMENT      // x = y
 )
 
 // Operator precedence table
 var precedences = map[token.TokenType]int{
+	token.OR:       LOGICAL_OR,
+	token.AND:      LOGICAL_AND,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
@@ -37,6 +43,8 @@ var precedences = map[token.TokenType]int{
 	token.ASTERISK: PRODUCT,
 	token.MODULO:   PRODUCT,
 	token.LPAREN:   CALL,
+	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 	token.ASSIGN:   ASSIGNMENT,
 }
 
@@ -45,37 +53,66 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// ParseError describes a syntax error and where in the source it was found
+type ParseError struct {
+	Line     int
+	Column   int
+	Expected token.TokenType // the token type that was expected, empty if not applicable
+	Actual   token.TokenType // the token type that was found
+	Message  string
+}
+
+// Error formats the error with its position, e.g. "line 3, column 7: ..."
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
 // Parser handles the parsing of input from the lexer
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []*ParseError
 
 	curToken  token.Token
 	peekToken token.Token
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	// loopDepth counts the loops enclosing the current token within the current
+	// function, so break and continue outside of a loop can be rejected
+	loopDepth int
+
+	// blockDepth counts the blocks enclosing the current token, so export can
+	// be limited to the top level of a file
+	blockDepth int
 }
 
 // New creates a new Parser
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*ParseError{},
 	}
 
 	// Register prefix parsers
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.CHAR, p.parseCharLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.ERROR, p.parseLexerError)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	// Register infix parsers
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -90,8 +127,12 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LTE, p.parseInfixExpression)
 	p.registerInfix(token.GTE, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -110,16 +151,49 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-// Errors returns the parser errors
+// Errors returns the parser errors as messages prefixed with their position
 func (p *Parser) Errors() []string {
+	msgs := make([]string, 0, len(p.errors))
+	for _, err := range p.errors {
+		msgs = append(msgs, err.Error())
+	}
+	return msgs
+}
+
+// ParseErrors returns the parser errors with their positions and tokens
+func (p *Parser) ParseErrors() []*ParseError {
 	return p.errors
 }
 
+// addError records an error, skipping exact repeats that arise when several
+// nested constructs fail on the same token (e.g. two unclosed parentheses)
+func (p *Parser) addError(err *ParseError) {
+	if n := len(p.errors); n > 0 && *p.errors[n-1] == *err {
+		return
+	}
+	p.errors = append(p.errors, err)
+}
+
+// errorAt adds an error located at the given token
+func (p *Parser) errorAt(tok token.Token, format string, a ...interface{}) {
+	p.addError(&ParseError{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Actual:  tok.Type,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
 // peekError adds an error if the next token is not as expected
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Line:     p.peekToken.Line,
+		Column:   p.peekToken.Column,
+		Expected: t,
+		Actual:   p.peekToken.Type,
+		Message: fmt.Sprintf("expected next token to be %s, got %s instead",
+			t, p.peekToken.Type),
+	})
 }
 
 // nextToken advances both current and peek tokens
@@ -155,6 +229,16 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseReturnStatement()
 	case token.IMPORT:
 		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -174,13 +258,7 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken() // Consume the colon
 		p.nextToken() // Move to the type token
-
-		// Create type annotation
-		typeAnnotation := &ast.TypeAnnotation{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
-		}
-		stmt.Type = typeAnnotation
+		stmt.Type = p.parseTypeAnnotation()
 	}
 
 	if !p.expectPeek(token.ASSIGN) {
@@ -212,13 +290,7 @@ func (p *Parser) parseConstStatement() *ast.ConstStatement {
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken() // Consume the colon
 		p.nextToken() // Move to the type token
-
-		// Create type annotation
-		typeAnnotation := &ast.TypeAnnotation{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
-		}
-		stmt.Type = typeAnnotation
+		stmt.Type = p.parseTypeAnnotation()
 	}
 
 	if !p.expectPeek(token.ASSIGN) {
@@ -236,6 +308,39 @@ func (p *Parser) parseConstStatement() *ast.ConstStatement {
 	return stmt
 }
 
+// parseExportStatement parses a var or const statement marked with export,
+// which makes the name visible to files that import this one
+func (p *Parser) parseExportStatement() ast.Statement {
+	exportToken := p.curToken
+
+	if p.blockDepth > 0 {
+		p.errorAt(exportToken, "export is only allowed at the top level of a file")
+		return nil
+	}
+
+	p.nextToken()
+
+	switch p.curToken.Type {
+	case token.VAR:
+		stmt := p.parseVarStatement()
+		if stmt == nil {
+			return nil
+		}
+		stmt.Exported = true
+		return stmt
+	case token.CONST:
+		stmt := p.parseConstStatement()
+		if stmt == nil {
+			return nil
+		}
+		stmt.Exported = true
+		return stmt
+	default:
+		p.errorAt(p.curToken, "expected var or const after export, got %s", p.curToken.Type)
+		return nil
+	}
+}
+
 // parseReturnStatement parses a return statement
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
@@ -251,6 +356,118 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+// parseWhileStatement parses a while loop
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	return stmt
+}
+
+// parseForStatement parses a C-style for loop
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	// Optional initializer, e.g. var i = 0
+	p.nextToken()
+	if !p.curTokenIs(token.SEMICOLON) {
+		stmt.Init = p.parseStatement()
+		if stmt.Init == nil {
+			return nil
+		}
+		// Declarations and expression statements consume their own semicolon
+		if !p.curTokenIs(token.SEMICOLON) && !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	// Optional condition, e.g. i < 10
+	p.nextToken()
+	if !p.curTokenIs(token.SEMICOLON) {
+		stmt.Condition = p.parseExpression(LOWEST)
+		if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	// Optional update, e.g. i = i + 1
+	p.nextToken()
+	if !p.curTokenIs(token.RPAREN) {
+		stmt.Update = p.parseExpression(LOWEST)
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	return stmt
+}
+
+// parseLoopBody parses the block of a loop, allowing break and continue inside it
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
+// parseBreakStatement parses a break statement
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if p.loopDepth == 0 {
+		p.errorAt(stmt.Token, "break statement outside of a loop")
+		return nil
+	}
+
+	return stmt
+}
+
+// parseContinueStatement parses a continue statement
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if p.loopDepth == 0 {
+		p.errorAt(stmt.Token, "continue statement outside of a loop")
+		return nil
+	}
+
+	return stmt
+}
+
 // parseExpressionStatement parses an expression statement
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
@@ -269,6 +486,9 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
+
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
@@ -316,8 +536,21 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
+// parseFloatLiteral parses a floating-point literal
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.errorAt(p.curToken, "could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 
@@ -330,11 +563,34 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseCharLiteral parses a character literal
+func (p *Parser) parseCharLiteral() ast.Expression {
+	value, _ := utf8.DecodeRuneInString(p.curToken.Literal)
+	return &ast.CharLiteral{Token: p.curToken, Value: value}
+}
+
+// parseLexerError records the error carried by a malformed literal
+func (p *Parser) parseLexerError() ast.Expression {
+	p.errorAt(p.curToken, "%s", p.curToken.Literal)
+	return nil
+}
+
+// parseIllegal records an error for a character the lexer does not recognise
+func (p *Parser) parseIllegal() ast.Expression {
+	p.errorAt(p.curToken, "illegal character %q", p.curToken.Literal)
+	return nil
+}
+
 // parseBoolean parses a boolean
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.BooleanLiteral{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+// parseNullLiteral parses the null literal
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 // parsePrefixExpression parses a prefix expression
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
@@ -425,20 +681,18 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken() // Consume the colon
 		p.nextToken() // Move to the type token
-
-		// Create return type annotation
-		returnType := &ast.TypeAnnotation{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
-		}
-		lit.ReturnType = returnType
+		lit.ReturnType = p.parseTypeAnnotation()
 	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	// A function body starts outside of any loop, even when the function is defined inside one
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
 
 	return lit
 }
@@ -463,13 +717,7 @@ func (p *Parser) parseFunctionParameters() []*ast.FunctionParameter {
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken() // Consume the colon
 		p.nextToken() // Move to the type token
-
-		// Create type annotation
-		typeAnnotation := &ast.TypeAnnotation{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
-		}
-		param.Type = typeAnnotation
+		param.Type = p.parseTypeAnnotation()
 	}
 
 	parameters = append(parameters, param)
@@ -487,13 +735,7 @@ func (p *Parser) parseFunctionParameters() []*ast.FunctionParameter {
 		if p.peekTokenIs(token.COLON) {
 			p.nextToken() // Consume the colon
 			p.nextToken() // Move to the type token
-
-			// Create type annotation
-			typeAnnotation := &ast.TypeAnnotation{
-				Token: p.curToken,
-				Value: p.curToken.Literal,
-			}
-			param.Type = typeAnnotation
+			param.Type = p.parseTypeAnnotation()
 		}
 
 		parameters = append(parameters, param)
@@ -506,6 +748,149 @@ func (p *Parser) parseFunctionParameters() []*ast.FunctionParameter {
 	return parameters
 }
 
+// parseTypeAnnotation parses the type after a colon, starting at its first
+// token. A union lists the types it accepts separated by '|', and a type
+// followed by '?' also accepts null - e.g., int | string, int?
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	annotation := p.parseOptionalType()
+	if annotation == nil || !p.peekTokenIs(token.PIPE) {
+		return annotation
+	}
+
+	union := &ast.TypeAnnotation{Token: annotation.Token, Value: "union", Options: []*ast.TypeAnnotation{}}
+	addOption(union, annotation)
+
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken()
+		p.nextToken()
+
+		option := p.parseOptionalType()
+		if option == nil {
+			return nil
+		}
+		addOption(union, option)
+	}
+	return union
+}
+
+// parseOptionalType parses a type that may be followed by '?', which makes it
+// a union of the type and null
+func (p *Parser) parseOptionalType() *ast.TypeAnnotation {
+	annotation := p.parseSingleType()
+	if annotation == nil || !p.peekTokenIs(token.QUESTION) {
+		return annotation
+	}
+	p.nextToken()
+
+	union := &ast.TypeAnnotation{Token: annotation.Token, Value: "union", Options: []*ast.TypeAnnotation{}}
+	addOption(union, annotation)
+	addOption(union, &ast.TypeAnnotation{Token: p.curToken, Value: "null"})
+	return union
+}
+
+// parseSingleType parses a type name, a function type or a type in
+// parentheses. A function type lists its parameter types in parentheses
+// followed by an arrow and its result type - e.g., (int, int) -> int
+func (p *Parser) parseSingleType() *ast.TypeAnnotation {
+	if !p.curTokenIs(token.LPAREN) {
+		return p.parseNamedType()
+	}
+
+	annotation := &ast.TypeAnnotation{Token: p.curToken, Value: "function", Params: []*ast.TypeAnnotation{}}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if len(annotation.Params) > 0 && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+
+		param := p.parseTypeAnnotation()
+		if param == nil {
+			return nil
+		}
+		annotation.Params = append(annotation.Params, param)
+	}
+	p.nextToken()
+
+	// A single type in parentheses without an arrow groups it, so that a
+	// function type can be made optional - e.g., ((int) -> int)?
+	if len(annotation.Params) == 1 && !p.peekTokenIs(token.ARROW) {
+		return annotation.Params[0]
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+
+	annotation.Return = p.parseTypeAnnotation()
+	if annotation.Return == nil {
+		return nil
+	}
+	return annotation
+}
+
+// typeArguments is the number of type arguments each parameterized type takes
+var typeArguments = map[string]int{
+	"array": 1, // the type of the elements
+	"map":   2, // the types of the keys and the values
+}
+
+// parseNamedType parses a type name and its type arguments, if any - e.g.,
+// int, array<int>, map<string, array<bool>>
+func (p *Parser) parseNamedType() *ast.TypeAnnotation {
+	annotation := &ast.TypeAnnotation{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.peekTokenIs(token.LT) {
+		return annotation
+	}
+	p.nextToken()
+
+	annotation.Args = []*ast.TypeAnnotation{}
+	for {
+		p.nextToken()
+
+		arg := p.parseTypeAnnotation()
+		if arg == nil {
+			return nil
+		}
+		annotation.Args = append(annotation.Args, arg)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.GT) {
+		return nil
+	}
+
+	if expected := typeArguments[annotation.Value]; len(annotation.Args) != expected {
+		p.errorAt(annotation.Token, "wrong number of type arguments for %s: expected %d, got %d",
+			annotation.Value, expected, len(annotation.Args))
+		return nil
+	}
+	return annotation
+}
+
+// addOption adds a type to a union, adding the options of a union instead and
+// leaving out types the union already accepts
+func addOption(union, option *ast.TypeAnnotation) {
+	if option.IsUnion() {
+		for _, o := range option.Options {
+			addOption(union, o)
+		}
+		return
+	}
+
+	for _, existing := range union.Options {
+		if existing.String() == option.String() {
+			return
+		}
+	}
+	union.Options = append(union.Options, option)
+}
+
 // parseCallExpression parses a function call
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
@@ -513,6 +898,111 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseArrayLiteral parses an array literal
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	return array
+}
+
+// parseHashLiteral parses a hash literal. Blocks are only parsed where a statement
+// expects one (after if, else, loops and function signatures), so a '{' in
+// expression position always starts a hash literal.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = []*ast.HashPair{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, &ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+// parseIndexExpression parses an index expression
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseImportNames parses the braced list of names in a selective import
+func (p *Parser) parseImportNames() []*ast.Identifier {
+	names := []*ast.Identifier{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		names = append(names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	if len(names) == 0 {
+		p.errorAt(p.curToken, "expected at least one name to import")
+		return nil
+	}
+
+	return names
+}
+
+// expectContextualKeyword advances past an identifier that acts as a keyword
+// in one place, such as `from` in an import
+func (p *Parser) expectContextualKeyword(word string) bool {
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == word {
+		p.nextToken()
+		return true
+	}
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead", word, p.peekToken.Type)
+	return false
+}
+
+// parseMemberExpression parses a member access
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: left}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
 // parseExpressionList parses a list of expressions
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
@@ -578,15 +1068,25 @@ func (p *Parser) curPrecedence() int {
 
 // noPrefixParseFnError adds an error for a token with no prefix parse function
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, "no prefix parse function for %s found", t)
 }
 
 func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
+	if target, ok := left.(*ast.IndexExpression); ok {
+		expr := &ast.IndexAssignmentExpression{
+			Token:  p.curToken,
+			Target: target,
+		}
+
+		p.nextToken()
+		expr.Value = p.parseExpression(LOWEST)
+
+		return expr
+	}
+
 	ident, ok := left.(*ast.Identifier)
 	if !ok {
-		msg := fmt.Sprintf("expected identifier on left side of assignment, got %s", left.TokenLiteral())
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, "expected identifier on left side of assignment, got %s", left.TokenLiteral())
 		return nil
 	}
 
@@ -601,19 +1101,39 @@ func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
 	return expr
 }
 
-// parseImportStatement parses an import statement
+// parseImportStatement parses an import statement. `as` and `from` are not
+// keywords, so they remain usable as names elsewhere.
 func (p *Parser) parseImportStatement() *ast.ImportStatement {
 	stmt := &ast.ImportStatement{Token: p.curToken}
 
 	p.nextToken()
 
+	if p.curTokenIs(token.LBRACE) {
+		stmt.Names = p.parseImportNames()
+		if stmt.Names == nil {
+			return nil
+		}
+		if !p.expectContextualKeyword("from") {
+			return nil
+		}
+		p.nextToken()
+	}
+
 	if p.curToken.Type != token.STRING {
-		p.errors = append(p.errors, fmt.Sprintf("expected string as import path, got %s", p.curToken.Type))
+		p.errorAt(p.curToken, "expected string as import path, got %s", p.curToken.Type)
 		return nil
 	}
 
 	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 
+	if stmt.Names == nil && p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "as" {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
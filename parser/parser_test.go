@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/onurravli/goception/lexer"
+	"github.com/onurravli/goception/token"
+)
+
+func TestParseErrorPositions(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedLine     int
+		expectedColumn   int
+		expectedExpected token.TokenType
+		expectedActual   token.TokenType
+		expectedMessage  string
+	}{
+		{
+			"var x = 5;\nprint(x;",
+			2, 8,
+			token.RPAREN, token.SEMICOLON,
+			"expected next token to be ), got ; instead",
+		},
+		{
+			"var = 5;",
+			1, 5,
+			token.IDENT, token.ASSIGN,
+			"expected next token to be IDENT, got = instead",
+		},
+		{
+			"while (true) {}\nbreak;",
+			2, 1,
+			"", token.BREAK,
+			"break statement outside of a loop",
+		},
+		{
+			"var s = \"a\\qb\";",
+			1, 11,
+			"", token.ERROR,
+			"invalid escape sequence \\q",
+		},
+		{
+			"export 5;",
+			1, 8,
+			"", token.INT,
+			"expected var or const after export, got INT",
+		},
+		{
+			"if (true) { export var x = 1; }",
+			1, 13,
+			"", token.EXPORT,
+			"export is only allowed at the top level of a file",
+		},
+	}
+
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) == 0 {
+			t.Fatalf("tests[%d] - expected parse errors, got none", i)
+		}
+
+		err := errors[0]
+		if err.Line != tt.expectedLine || err.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, err.Line, err.Column)
+		}
+		if err.Expected != tt.expectedExpected || err.Actual != tt.expectedActual {
+			t.Errorf("tests[%d] - tokens wrong. expected=%q/%q, got=%q/%q",
+				i, tt.expectedExpected, tt.expectedActual, err.Expected, err.Actual)
+		}
+		if err.Message != tt.expectedMessage {
+			t.Errorf("tests[%d] - message wrong. expected=%q, got=%q",
+				i, tt.expectedMessage, err.Message)
+		}
+
+		// Errors keeps returning plain strings, now prefixed with the position
+		expectedString := err.Error()
+		if p.Errors()[0] != expectedString {
+			t.Errorf("tests[%d] - Errors() wrong. expected=%q, got=%q",
+				i, expectedString, p.Errors()[0])
+		}
+	}
+}
+
+func TestImportStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "math-utils";`, `import math-utils;`},
+		{`import "math-utils" as m;`, `import math-utils as m;`},
+		{`import { square, PI } from "math-utils";`, `import { square, PI } from math-utils;`},
+		{`m.square(5);`, `(m.square)(5)`},
+		{`var from = m.PI * 2;`, `var from = ((m.PI) * 2);`},
+		{`export const PI = 3;`, `export const PI = 3;`},
+		{`export var count: int = 0;`, `export var count: int = 0;`},
+	}
+
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("tests[%d] - parse errors: %v", i, p.Errors())
+		}
+		if program.String() != tt.expected {
+			t.Errorf("tests[%d] - wrong program. expected=%q, got=%q", i, tt.expected, program.String())
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var n: int = 1;`, `var n: int = 1;`},
+		{`var f: function = g;`, `var f: function = g;`},
+		{`var f: (int, int) -> int = add;`, `var f: (int, int) -> int = add;`},
+		{`var f: () -> string = name;`, `var f: () -> string = name;`},
+		{`var f: ((int) -> int, int) -> (int) -> bool = g;`, `var f: ((int) -> int, int) -> (int) -> bool = g;`},
+		{`var n: int? = null;`, `var n: int? = null;`},
+		{`var n: int | null = null;`, `var n: int? = null;`},
+		{`var u: int | string | int? = 1;`, `var u: int | string | null = 1;`},
+		{`var f: (int?) -> int | null = g;`, `var f: (int?) -> int? = g;`},
+		{`var f: ((int) -> int)? = null;`, `var f: ((int) -> int)? = null;`},
+		{`var xs: array<int> = [];`, `var xs: array<int> = [];`},
+		{`var m: map<string, array<bool>> = {};`, `var m: map<string, array<bool>> = {};`},
+		{`var m: map<string, int?>? = null;`, `var m: map<string, int?>? = null;`},
+		{`var fs: array<(int) -> int> = [];`, `var fs: array<(int) -> int> = [];`},
+		{`const apply = function(f: (int) -> int, x: int): (int) -> int { return f; };`,
+			`const apply = function(f: (int) -> int, x: int): (int) -> int return f;;`},
+	}
+
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("tests[%d] - parse errors: %v", i, p.Errors())
+		}
+		if program.String() != tt.expected {
+			t.Errorf("tests[%d] - wrong program. expected=%q, got=%q", i, tt.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{`var f: (int, int) int = add;`, `var f: (int int) -> int = add;`, `var f: (int) -> = add;`,
+		`var xs: array<int, int> = [];`, `var m: map<string> = {};`, `var n: int<string> = 1;`, `var xs: array<int = [];`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected a parse error", input)
+		}
+	}
+}
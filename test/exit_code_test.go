@@ -0,0 +1,136 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExitCodes tests the exit status and output streams of running a file
+func TestExitCodes(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "goception_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Build the binary, since `go run` reports every failing exit status as 1
+	binary := filepath.Join(tempDir, "goception")
+	if output, err := exec.Command("go", "build", "-o", binary, "../cmd/goception").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build Goception: %v\n%s", err, output)
+	}
+
+	tests := []struct {
+		name           string
+		code           string
+		args           []string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{
+			name:           "Success",
+			code:           `print("ok"); 1 + 2;`,
+			expectedCode:   0,
+			expectedStdout: "ok\n",
+		},
+		{
+			name:           "PrintResult",
+			code:           `print("ok"); 1 + 2;`,
+			args:           []string{"-print"},
+			expectedCode:   0,
+			expectedStdout: "ok\n3\n",
+		},
+		{
+			name:           "RuntimeError",
+			code:           `const add = function(a, b) { return a + b; }; print("before"); add(1, true); print("after");`,
+			expectedCode:   1,
+			expectedStdout: "before\n",
+			expectedStderr: "ERROR: type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			name:           "ParseError",
+			code:           `print("never";`,
+			expectedCode:   3,
+			expectedStderr: "parse error at line 1, column 14",
+		},
+		{
+			name:           "TypeError",
+			code:           `print("never"); 1 + true;`,
+			expectedCode:   5,
+			expectedStderr: "type error at line 1, column 19: type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			name:         "Check",
+			code:         `const add = function(a: int, b: int): int { return a + b; }; add(1, 2);`,
+			args:         []string{"check"},
+			expectedCode: 0,
+		},
+		{
+			name:           "CheckTypeErrors",
+			code:           "const add = function(a: int, b: int): int { return a + b; };\nprint(\"never\");\nadd(1, \"2\");\nconst n: string = add(1, 2);",
+			args:           []string{"check"},
+			expectedCode:   5,
+			expectedStderr: "type error at line 3, column 8: type mismatch for argument 1: expected int, got STRING\n    3 | add(1, \"2\");\n      |        ^\ntype error at line 4, column 19: type mismatch: expected string, got INTEGER",
+		},
+		{
+			name:           "Exit",
+			code:           `print("before"); const f = function() { exit(7); }; f(); print("after");`,
+			expectedCode:   7,
+			expectedStdout: "before\n",
+		},
+		{
+			name:           "ExitInLoop",
+			code:           `for (var i = 0; i < 10; i = i + 1) { if (i == 2) { exit(); } print(i); }`,
+			expectedCode:   0,
+			expectedStdout: "0\n1\n",
+		},
+		{
+			name:           "MissingFile",
+			args:           []string{filepath.Join(tempDir, "missing.gct")},
+			expectedCode:   4,
+			expectedStderr: "Error reading file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.code != "" {
+				testFile := filepath.Join(tempDir, tt.name+".gct")
+				if err := os.WriteFile(testFile, []byte(tt.code), 0644); err != nil {
+					t.Fatalf("Failed to write test file: %v", err)
+				}
+				args = append(append([]string{}, args...), testFile)
+			}
+
+			cmd := exec.Command(binary, args...)
+			var stdout, stderr strings.Builder
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("Failed to run Goception: %v", err)
+			}
+
+			if code != tt.expectedCode {
+				t.Errorf("Expected exit code %d, got %d. Stderr: %s", tt.expectedCode, code, stderr.String())
+			}
+			if stdout.String() != tt.expectedStdout {
+				t.Errorf("Expected stdout %q, got %q", tt.expectedStdout, stdout.String())
+			}
+			if tt.expectedStderr != "" && !strings.Contains(stderr.String(), tt.expectedStderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tt.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
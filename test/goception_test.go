@@ -12,6 +12,9 @@ func TestGoception(t *testing.T) {
 	TestArithmeticOperations(t)
 	TestComparisonOperations(t)
 	TestConditionals(t)
+	TestLoops(t)
+	TestArrays(t)
+	TestHashes(t)
 	TestFunctions(t)
 	TestTypeSystem(t)
 	TestIntegration(t)
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseErrorExcerpt tests that parse errors show the offending source line with a caret
+func TestParseErrorExcerpt(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "goception_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create a test file with a missing closing parenthesis on the second line
+	code := "const x: int = 42;\nprint(x;\n"
+	testFile := filepath.Join(tempDir, "parse-error.gct")
+	if err := os.WriteFile(testFile, []byte(code), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Run the test
+	cmd := exec.Command("go", "run", "../cmd/goception", testFile)
+	output, _ := cmd.CombinedOutput() // Ignore the error - we expect one
+	outputStr := string(output)
+
+	expected := []string{
+		"parse error at line 2, column 8: expected next token to be ), got ; instead",
+		"    2 | print(x;",
+		"      |        ^",
+	}
+	for _, line := range expected {
+		if !strings.Contains(outputStr, line) {
+			t.Errorf("Expected output to contain %q, got: %s", line, outputStr)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRuntimeErrorTrace tests that runtime errors report where they happened
+// and the chain of function calls leading there, across imported files
+func TestRuntimeErrorTrace(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "goception_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	libFile := filepath.Join(tempDir, "lib.gct")
+	mainFile := filepath.Join(tempDir, "main.gct")
+	files := map[string]string{
+		libFile: "export const add = function(a, b) {\n    return a + b;\n};\n",
+		mainFile: "import \"" + libFile + "\";\n" +
+			"const compute = function(x) {\n" +
+			"    return add(x, true);\n" +
+			"};\n" +
+			"print(compute(1));\n",
+	}
+	for name, code := range files {
+		if err := os.WriteFile(name, []byte(code), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// Run the test
+	cmd := exec.Command("go", "run", "../cmd/goception", mainFile)
+	output, _ := cmd.CombinedOutput() // Ignore the error - we expect one
+	outputStr := string(output)
+
+	expected := "ERROR: type mismatch: INTEGER + BOOLEAN\n" +
+		"    at " + libFile + ":2:14 in add\n" +
+		"    at " + mainFile + ":3:12 in compute\n" +
+		"    at " + mainFile + ":5:7\n"
+	if !strings.Contains(outputStr, expected) {
+		t.Errorf("Expected output to contain:\n%s\ngot:\n%s", expected, outputStr)
+	}
+}
@@ -28,7 +28,7 @@ func TestSimpleGoception(t *testing.T) {
 	}
 
 	// Run the test
-	cmd := exec.Command("go", "run", "../main.go", testFile)
+	cmd := exec.Command("go", "run", "../cmd/goception", testFile)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		t.Fatalf("Failed to run Goception: %v\nOutput: %s", err, output)
@@ -36,8 +36,6 @@ func TestSimpleGoception(t *testing.T) {
 
 	// Check result
 	outputStr := strings.TrimSpace(string(output))
-	outputStr = strings.TrimSuffix(outputStr, "null") // Remove trailing null
-	outputStr = strings.TrimSpace(outputStr)
 
 	expected := "42"
 	if outputStr != expected {
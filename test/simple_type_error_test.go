@@ -28,7 +28,7 @@ func TestTypeError(t *testing.T) {
 	}
 
 	// Run the test
-	cmd := exec.Command("go", "run", "../main.go", testFile)
+	cmd := exec.Command("go", "run", "../cmd/goception", testFile)
 	output, _ := cmd.CombinedOutput() // Ignore the error - we expect one
 	outputStr := string(output)
 
@@ -40,7 +40,7 @@ func (ts *TestSuite) Run(t *testing.T) {
 			if err := os.WriteFile(setupFile, []byte(ts.SetupScript), 0644); err != nil {
 				t.Fatalf("Failed to write setup script: %v", err)
 			}
-			cmd := exec.Command("go", "run", "../main.go", setupFile)
+			cmd := exec.Command("go", "run", "../cmd/goception", setupFile)
 			err := cmd.Run()
 			if err != nil {
 				t.Fatalf("Setup script failed: %v", err)
@@ -57,25 +57,26 @@ func (ts *TestSuite) Run(t *testing.T) {
 				}
 
 				// Run the test
-				cmd := exec.Command("go", "run", "../main.go", testFile)
-				output, _ := cmd.CombinedOutput() // Ignore execution error - we handle it later
+				cmd := exec.Command("go", "run", "../cmd/goception", testFile)
+				output, runErr := cmd.CombinedOutput()
 				outputStr := string(output)
 
+				// Runtime errors print ERROR, while type errors stop the script before it runs
+				failed := runErr != nil || strings.Contains(outputStr, "ERROR")
+
 				// Check if we expect an error
 				if tc.ShouldError {
-					if !strings.Contains(outputStr, "ERROR") {
+					if !failed {
 						t.Errorf("Expected error but got none. Output: %s", outputStr)
 					} else if tc.ErrorMessage != "" && !strings.Contains(outputStr, tc.ErrorMessage) {
 						t.Errorf("Expected error message to contain '%s', got: %s", tc.ErrorMessage, outputStr)
 					}
 				} else {
 					// We don't expect an error
-					if strings.Contains(outputStr, "ERROR") {
+					if failed {
 						t.Errorf("Unexpected error: %s", outputStr)
 					} else {
-						// Trim trailing null from output string that appears in Goception results
-						trimmedOutput := strings.TrimSuffix(strings.TrimSpace(outputStr), "null")
-						trimmedOutput = strings.TrimSpace(trimmedOutput)
+						trimmedOutput := strings.TrimSpace(outputStr)
 
 						// Compare output with expected
 						if tc.ExpectedOutput != "" && trimmedOutput != tc.ExpectedOutput {
@@ -195,6 +196,63 @@ func TestStringOperations(t *testing.T) {
 				`,
 				ExpectedOutput: "Alice is 30 years old and student status is false",
 			},
+			{
+				Name: "IntWithStringConcatenation",
+				Code: `
+					print(5 + " apples");
+				`,
+				ExpectedOutput: "5 apples",
+			},
+			{
+				Name: "StringEscapes",
+				Code: `
+					print("She said \"hi\"");
+					print("a\tb");
+					print("back\\slash");
+					print("\x41\u{e9}");
+					print(len("line\n"));
+				`,
+				ExpectedOutput: "She said \"hi\"\na\tb\nback\\slash\nAé\n5",
+			},
+			{
+				Name: "CharLiterals",
+				Code: `
+					const c: char = 'a';
+					print(c);
+					print('é');
+					print(ord('\n'));
+					print(chr(66));
+				`,
+				ExpectedOutput: "a\né\n10\nB",
+			},
+			{
+				Name: "StringIndexing",
+				Code: `
+					const word: string = "héllo";
+					const second: char = word[1];
+					print(second);
+					print(len(word));
+					print(word[0] == 'h');
+					print(word[4] + "!");
+				`,
+				ExpectedOutput: "é\n5\ntrue\no!",
+			},
+			{
+				Name: "StringIndexOutOfRange",
+				Code: `
+					print("ab"[2]);
+				`,
+				ShouldError:  true,
+				ErrorMessage: "index out of range: 2 (length 2)",
+			},
+			{
+				Name: "TypeMismatchChar",
+				Code: `
+					const c: char = "a"; // Should cause an error
+				`,
+				ShouldError:  true,
+				ErrorMessage: "type mismatch: expected char, got STRING",
+			},
 		},
 	}
 	suite.Run(t)
@@ -262,6 +320,44 @@ func TestArithmeticOperations(t *testing.T) {
 				`,
 				ExpectedOutput: "-2",
 			},
+			{
+				Name: "FloatLiterals",
+				Code: `
+					print(3.14);
+					print(.5);
+					print(1e-9);
+					print(2.0);
+				`,
+				ExpectedOutput: "3.14\n0.5\n1e-09\n2.0",
+			},
+			{
+				Name: "FloatArithmetic",
+				Code: `
+					const price: float = 19.99;
+					print(price * 3);
+					print(-price);
+					print(7.5 % 2);
+				`,
+				ExpectedOutput: "59.97\n-19.99\n1.5",
+			},
+			{
+				Name: "MixedIntFloatArithmetic",
+				Code: `
+					print(1 + 0.5);
+					print(10 / 4.0);
+					print(2.5 * 2);
+				`,
+				ExpectedOutput: "1.5\n2.5\n5.0",
+			},
+			{
+				Name: "MixedIntFloatComparison",
+				Code: `
+					print(1 < 1.5);
+					print(2 == 2.0);
+					print(3.5 >= 4);
+				`,
+				ExpectedOutput: "true\ntrue\nfalse",
+			},
 		},
 	}
 	suite.Run(t)
@@ -393,6 +489,317 @@ func TestConditionals(t *testing.T) {
 				`,
 				ExpectedOutput: "x > 5 and y > 15",
 			},
+			{
+				Name: "LogicalOperators",
+				Code: `
+					const x: int = 10;
+					print(x > 5 && x < 20);
+					print(x < 5 || x == 10);
+					print(true && false);
+					print(false || false);
+					print(!(x > 5) || x % 2 == 0 && x > 100);
+				`,
+				ExpectedOutput: "true\ntrue\nfalse\nfalse\nfalse",
+			},
+			{
+				Name: "LogicalShortCircuit",
+				Code: `
+					const loud = function(v: bool): bool {
+						print("evaluated");
+						return v;
+					};
+					print(false && loud(true));
+					print(true || loud(false));
+					print(true && loud(false));
+				`,
+				ExpectedOutput: "false\ntrue\nevaluated\nfalse",
+			},
+		},
+	}
+	suite.Run(t)
+}
+
+// TestLoops tests while and for loops with break and continue
+func TestLoops(t *testing.T) {
+	suite := TestSuite{
+		Name: "Loops",
+		TestCases: []TestCase{
+			{
+				Name: "WhileLoop",
+				Code: `
+					var i: int = 0;
+					while (i < 3) {
+						print(i);
+						i = i + 1;
+					}
+				`,
+				ExpectedOutput: "0\n1\n2",
+			},
+			{
+				Name: "ForLoop",
+				Code: `
+					var total: int = 0;
+					for (var i: int = 1; i <= 10; i = i + 1) {
+						total = total + i;
+					}
+					print(total);
+				`,
+				ExpectedOutput: "55",
+			},
+			{
+				Name: "ForLoopWithExistingVariable",
+				Code: `
+					var i: int = 100;
+					for (i = 0; i < 3; i = i + 1) {
+					}
+					print(i);
+				`,
+				ExpectedOutput: "3",
+			},
+			{
+				Name: "BreakAndContinue",
+				Code: `
+					for (var i: int = 1; i <= 10; i = i + 1) {
+						if (i % 2 == 0) {
+							continue;
+						}
+						if (i > 7) {
+							break;
+						}
+						print(i);
+					}
+				`,
+				ExpectedOutput: "1\n3\n5\n7",
+			},
+			{
+				Name: "BreakInNestedLoop",
+				Code: `
+					var i: int = 0;
+					while (i < 2) {
+						var j: int = 0;
+						while (true) {
+							if (j == 2) {
+								break;
+							}
+							print(i + ":" + j);
+							j = j + 1;
+						}
+						i = i + 1;
+					}
+				`,
+				ExpectedOutput: "0:0\n0:1\n1:0\n1:1",
+			},
+			{
+				Name: "ReturnInsideLoop",
+				Code: `
+					const firstMultipleOf = function(n: int, max: int): int {
+						for (var i: int = 1; i <= max; i = i + 1) {
+							if (i % n == 0) {
+								return i;
+							}
+						}
+						return 0;
+					};
+					print(firstMultipleOf(7, 100));
+					print(firstMultipleOf(7, 5));
+				`,
+				ExpectedOutput: "7\n0",
+			},
+			{
+				Name: "ErrorInsideLoop",
+				Code: `
+					for (var i: int = 0; i < 3; i = i + 1) {
+						var s: string = i;
+					}
+				`,
+				ShouldError:  true,
+				ErrorMessage: "type mismatch",
+			},
+		},
+	}
+	suite.Run(t)
+}
+
+// TestArrays tests array literals, indexing and array builtins
+func TestArrays(t *testing.T) {
+	suite := TestSuite{
+		Name: "Arrays",
+		TestCases: []TestCase{
+			{
+				Name: "ArrayLiteral",
+				Code: `
+					const numbers: array = [1, 2 + 3, "x"];
+					print(numbers);
+					print(len(numbers));
+				`,
+				ExpectedOutput: "[1, 5, x]\n3",
+			},
+			{
+				Name: "ArrayIndexing",
+				Code: `
+					const numbers: array = [10, 20, 30];
+					print(numbers[0] + numbers[2]);
+					const grid: array = [[1, 2], [3, 4]];
+					print(grid[1][0]);
+				`,
+				ExpectedOutput: "40\n3",
+			},
+			{
+				Name: "ArrayIndexAssignment",
+				Code: `
+					const numbers: array = [1, 2, 3];
+					numbers[1] = 20;
+					print(numbers);
+				`,
+				ExpectedOutput: "[1, 20, 3]",
+			},
+			{
+				Name: "ArrayBuiltins",
+				Code: `
+					var stack: array = [];
+					push(stack, 1);
+					push(stack, 2, 3);
+					print(stack);
+					print(pop(stack));
+					print(stack);
+					print(first(stack));
+					print(last(stack));
+					print(rest(stack));
+					print(slice([1, 2, 3, 4], 1, 3));
+					print(slice([1, 2, 3, 4], 2));
+				`,
+				ExpectedOutput: "[1, 2, 3]\n3\n[1, 2]\n1\n2\n[2]\n[2, 3]\n[3, 4]",
+			},
+			{
+				Name: "ArrayLoop",
+				Code: `
+					const items: array = [3, 1, 4];
+					var total: int = 0;
+					for (var i: int = 0; i < len(items); i = i + 1) {
+						total = total + items[i];
+					}
+					print(total);
+				`,
+				ExpectedOutput: "8",
+			},
+			{
+				Name: "NegativeIndexError",
+				Code: `
+					const numbers: array = [1, 2, 3];
+					print(numbers[-1]);
+				`,
+				ShouldError:  true,
+				ErrorMessage: "index out of range: -1 (length 3)",
+			},
+			{
+				Name: "OutOfRangeIndexError",
+				Code: `
+					const numbers: array = [1, 2, 3];
+					numbers[3] = 4;
+				`,
+				ShouldError:  true,
+				ErrorMessage: "index out of range: 3 (length 3)",
+			},
+			{
+				Name: "PopEmptyArrayError",
+				Code: `
+					pop([]);
+				`,
+				ShouldError:  true,
+				ErrorMessage: "pop from empty array",
+			},
+		},
+	}
+	suite.Run(t)
+}
+
+// TestHashes tests hash literals, indexing and hash builtins
+func TestHashes(t *testing.T) {
+	suite := TestSuite{
+		Name: "Hashes",
+		TestCases: []TestCase{
+			{
+				Name: "HashLiteral",
+				Code: `
+					const ages: map = {"bob": 30, "alice": 25, 1: true, 'c': 1.5};
+					print(ages);
+					print(len(ages));
+					print({});
+				`,
+				ExpectedOutput: "{bob: 30, alice: 25, 1: true, c: 1.5}\n4\n{}",
+			},
+			{
+				Name: "HashIndexing",
+				Code: `
+					const ages: map = {"bob": 30, "alice": 25};
+					print(ages["bob"] + ages["alice"]);
+					print(ages["carol"]);
+				`,
+				ExpectedOutput: "55\nnull",
+			},
+			{
+				Name: "HashIndexAssignment",
+				Code: `
+					const ages: map = {"bob": 30};
+					ages["alice"] = 25;
+					ages["bob"] = 31;
+					print(ages);
+				`,
+				ExpectedOutput: "{bob: 31, alice: 25}",
+			},
+			{
+				Name: "HashBuiltins",
+				Code: `
+					const ages: map = {"bob": 30, "alice": 25, "carol": 41};
+					print(keys(ages));
+					print(values(ages));
+					print(has(ages, "alice"));
+					print(delete(ages, "alice"));
+					print(has(ages, "alice"));
+					print(delete(ages, "alice"));
+					print(ages);
+				`,
+				ExpectedOutput: "[bob, alice, carol]\n[30, 25, 41]\ntrue\ntrue\nfalse\nfalse\n{bob: 30, carol: 41}",
+			},
+			{
+				Name: "HashIterationOrder",
+				Code: `
+					const counts: map = {};
+					const words: array = ["b", "a", "b", "c", "a", "b"];
+					for (var i: int = 0; i < len(words); i = i + 1) {
+						if (has(counts, words[i])) {
+							counts[words[i]] = counts[words[i]] + 1;
+						} else {
+							counts[words[i]] = 1;
+						}
+					}
+					const ks: array = keys(counts);
+					for (var i: int = 0; i < len(ks); i = i + 1) {
+						print(ks[i] + "=" + counts[ks[i]]);
+					}
+				`,
+				ExpectedOutput: "b=3\na=2\nc=1",
+			},
+			{
+				Name: "HashNumericKeys",
+				Code: `
+					const h: map = {1: "one", 2.5: "two and a half"};
+					h[1.0] = "uno";
+					print(h);
+					print(h[1.0] + " " + h[2.5]);
+					print(delete(h, 1.0));
+					print(h);
+				`,
+				ExpectedOutput: "{1: uno, 2.5: two and a half}\nuno two and a half\ntrue\n{2.5: two and a half}",
+			},
+			{
+				Name: "UnhashableKeyError",
+				Code: `
+					const h: map = {[1, 2]: "pair"};
+				`,
+				ShouldError:  true,
+				ErrorMessage: "unusable as hash key: ARRAY",
+			},
 		},
 	}
 	suite.Run(t)
@@ -508,6 +915,25 @@ func TestTypeSystem(t *testing.T) {
 				`,
 				ExpectedOutput: "42\nhello\ntrue",
 			},
+			{
+				Name: "FloatTypeAnnotation",
+				Code: `
+					const rate: float = 0.25;
+					const scale = function(x: float): float {
+						return x * 4;
+					};
+					print(scale(rate));
+				`,
+				ExpectedOutput: "1.0",
+			},
+			{
+				Name: "TypeMismatchFloat",
+				Code: `
+					var total: float = "12.50"; // Should cause an error
+				`,
+				ShouldError:  true,
+				ErrorMessage: "type mismatch: expected float, got STRING",
+			},
 			{
 				Name: "FunctionTypeAnnotation",
 				Code: `
@@ -622,6 +1048,9 @@ func TestMainSuite(t *testing.T) {
 	TestArithmeticOperations(t)
 	TestComparisonOperations(t)
 	TestConditionals(t)
+	TestLoops(t)
+	TestArrays(t)
+	TestHashes(t)
 	TestFunctions(t)
 	TestTypeSystem(t)
 	TestIntegration(t)
@@ -14,12 +14,15 @@ type Token struct {
 // Token types
 const (
 	ILLEGAL = "ILLEGAL" // unknown token
+	ERROR   = "ERROR"   // malformed literal, the literal holds the error message
 	EOF     = "EOF"     // end of file
 
 	// Identifiers + literals
 	IDENT  = "IDENT"  // add, x, y, ...
 	INT    = "INT"    // 1, 2, 3, ...
+	FLOAT  = "FLOAT"  // 3.14, 1e-9, .5, ...
 	STRING = "STRING" // "foo", "bar", ...
+	CHAR   = "CHAR"   // 'a', '\n', ...
 
 	// Operators
 	ASSIGN   = "="
@@ -35,11 +38,17 @@ const (
 	NOT_EQ   = "!="
 	LTE      = "<="
 	GTE      = ">="
+	AND      = "&&"
+	OR       = "||"
+	ARROW    = "->"
+	QUESTION = "?"
+	PIPE     = "|"
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
@@ -56,7 +65,13 @@ const (
 	RETURN   = "RETURN"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
+	NULL     = "NULL"
 	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 
 	// Types
 	TYPE_INT    = "INT_TYPE"
@@ -75,7 +90,13 @@ var keywords = map[string]TokenType{
 	"return":   RETURN,
 	"true":     TRUE,
 	"false":    FALSE,
+	"null":     NULL,
 	"import":   IMPORT,
+	"export":   EXPORT,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
 
 	// Types
 	"int":    TYPE_INT,
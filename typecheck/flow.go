@@ -0,0 +1,265 @@
+package typecheck
+
+import "github.com/onurravli/goception/ast"
+
+// state holds the type of the current value of bindings, where it is
+// narrower than what is assumed of them: a nullable variable checked against
+// null, or a variable assigned a value of one of the types of its union
+type state map[*binding]*Type
+
+func (s state) copy() state {
+	c := make(state, len(s))
+	for b, t := range s {
+		c[b] = t
+	}
+	return c
+}
+
+// read returns the type of the current value of a binding
+func (s state) read(b *binding) *Type {
+	if t, ok := s[b]; ok {
+		return t
+	}
+	return b.assumed
+}
+
+// equal reports whether two states know the same of every binding
+func (s state) equal(other state) bool {
+	for b := range merge(s, other) {
+		if s.read(b).String() != other.read(b).String() {
+			return false
+		}
+	}
+	return true
+}
+
+// widen returns the state without what is known of the contents of the
+// arrays and maps bindings hold
+func (s state) widen() state {
+	widened := make(state, len(s))
+	for b, t := range s {
+		widened[b] = t.widen()
+	}
+	return widened
+}
+
+// merge returns the state after code that may have left any of the given
+// states, in which a binding may have the value it has in any of them
+func merge(states ...state) state {
+	merged := state{}
+	for _, s := range states {
+		for b := range s {
+			types := make([]*Type, len(states))
+			for i, other := range states {
+				types[i] = other.read(b)
+			}
+			merged[b] = unionOf(types...)
+		}
+	}
+	return merged
+}
+
+// narrow returns the type of the current value of a binding after it is
+// assigned a value of type t. The assumed type is kept for a value of unknown
+// type, or one that does not have the declared type, which is reported. The
+// contents of an array or map are only known as declared, because they may be
+// changed through any variable that holds it.
+func (b *binding) narrow(t *Type) *Type {
+	if (!t.known() && t.Options == nil) || !assignable(b.declared, t) || b.declared.Signature != nil {
+		return b.assumed
+	}
+	return t.withContentsOf(b.declared)
+}
+
+// checkNotNull reports an operand that may be null where the operator needs
+// a value, and returns the type of its value when it is not null
+func (c *checker) checkNotNull(operand ast.Expression, t *Type, operator string) *Type {
+	if !t.nullable() {
+		return t
+	}
+
+	c.errorf(operand, "%s may be null: check that it is not null before using %s", ast.Source(operand), operator)
+	return t.withoutNull()
+}
+
+// nullChecks returns the bindings that a condition proves not to be null when
+// it is true and when it is false: a variable compared to null, a variable
+// used as a condition, since null is falsy, and combinations of both with
+// &&, || and !
+func (c *checker) nullChecks(condition ast.Expression) (whenTrue, whenFalse []*binding) {
+	switch condition := condition.(type) {
+	case *ast.Identifier:
+		if b, ok := c.scope.lookup(condition.Value); ok {
+			return []*binding{b}, nil
+		}
+	case *ast.PrefixExpression:
+		if condition.Operator == "!" {
+			whenTrue, whenFalse = c.nullChecks(condition.Right)
+			return whenFalse, whenTrue
+		}
+	case *ast.InfixExpression:
+		switch condition.Operator {
+		case "&&":
+			left, _ := c.nullChecks(condition.Left)
+			right, _ := c.nullChecks(condition.Right)
+			return append(left, right...), nil
+		case "||":
+			_, left := c.nullChecks(condition.Left)
+			_, right := c.nullChecks(condition.Right)
+			return nil, append(left, right...)
+		case "!=", "==":
+			b := c.comparedToNull(condition.Left, condition.Right)
+			if b == nil {
+				b = c.comparedToNull(condition.Right, condition.Left)
+			}
+			if b == nil {
+				return nil, nil
+			}
+			if condition.Operator == "!=" {
+				return []*binding{b}, nil
+			}
+			return nil, []*binding{b}
+		}
+	}
+	return nil, nil
+}
+
+// comparedToNull returns the binding of a variable compared to the null literal
+func (c *checker) comparedToNull(variable, value ast.Expression) *binding {
+	ident, ok := variable.(*ast.Identifier)
+	if _, null := value.(*ast.NullLiteral); !ok || !null {
+		return nil
+	}
+	b, _ := c.scope.lookup(ident.Value)
+	return b
+}
+
+// narrowNotNull records that the given bindings are not null. A binding
+// known to hold null cannot be proved not null by code that runs, so it is
+// only known to have its declared type there.
+func (c *checker) narrowNotNull(bindings []*binding) {
+	for _, b := range bindings {
+		t := c.flow.read(b)
+		if t.is(Null) {
+			t = b.declared
+		}
+		c.flow[b] = t.withoutNull()
+	}
+}
+
+// checkIfExpression checks each branch of an if expression knowing what its
+// condition proves. After it, a binding may have the value it has at the end
+// of any branch that does not end with return, break or continue.
+func (c *checker) checkIfExpression(expr *ast.IfExpression) {
+	c.checkExpression(expr.Condition)
+	whenTrue, whenFalse := c.nullChecks(expr.Condition)
+	before := c.flow
+
+	ends := []state{}
+	c.flow = before.copy()
+	c.narrowNotNull(whenTrue)
+	c.checkStatements(expr.Consequence.Statements)
+	if !exits(expr.Consequence) {
+		ends = append(ends, c.flow)
+	}
+
+	c.flow = before.copy()
+	c.narrowNotNull(whenFalse)
+	if expr.Alternative != nil {
+		c.checkStatements(expr.Alternative.Statements)
+	}
+	if expr.Alternative == nil || !exits(expr.Alternative) {
+		ends = append(ends, c.flow)
+	}
+
+	if len(ends) == 0 {
+		// Nothing after the if expression runs
+		c.flow = before
+		return
+	}
+	c.flow = merge(ends...)
+}
+
+// checkLogicalExpression checks the operands of && and ||. The right operand
+// only runs when the left one does not decide the result, knowing what it proves.
+func (c *checker) checkLogicalExpression(expr *ast.InfixExpression) {
+	c.checkExpression(expr.Left)
+	whenTrue, whenFalse := c.nullChecks(expr.Left)
+	before := c.flow
+
+	c.flow = before.copy()
+	if expr.Operator == "&&" {
+		c.narrowNotNull(whenTrue)
+	} else {
+		c.narrowNotNull(whenFalse)
+	}
+	c.checkExpression(expr.Right)
+
+	c.flow = merge(before, c.flow)
+}
+
+// loopPasses is the number of times a loop is checked before the contents of
+// collections are forgotten
+const loopPasses = 4
+
+// checkLoop checks the condition, body and update of a loop. The loop may
+// start again with what any iteration leaves, so the body is checked until
+// what is known at the start of an iteration no longer changes, and errors
+// are only reported once that is known. A loop may nest a collection one
+// level deeper in each iteration, so after a few passes the contents of
+// collections are forgotten.
+func (c *checker) checkLoop(condition ast.Expression, body *ast.BlockStatement, update ast.Expression) {
+	start := c.flow
+	for pass := 1; ; pass++ {
+		reported := len(c.errors)
+		end := c.checkIteration(start, condition, body, update)
+		c.errors = c.errors[:reported]
+
+		next := merge(start, end)
+		if pass >= loopPasses {
+			next = next.widen()
+		}
+		if next.equal(start) {
+			break
+		}
+		start = next
+	}
+
+	c.flow = merge(start, c.checkIteration(start, condition, body, update))
+}
+
+// checkIteration checks one iteration of a loop from a state and returns the
+// state after it, or after any break or continue in it
+func (c *checker) checkIteration(start state, condition ast.Expression, body *ast.BlockStatement, update ast.Expression) state {
+	c.flow = start.copy()
+
+	var whenTrue []*binding
+	if condition != nil {
+		c.checkExpression(condition)
+		whenTrue, _ = c.nullChecks(condition)
+	}
+	c.narrowNotNull(whenTrue)
+
+	exits := []state{}
+	c.loops = append(c.loops, &exits)
+	c.checkStatements(body.Statements)
+	c.loops = c.loops[:len(c.loops)-1]
+
+	if update != nil {
+		c.checkExpression(update)
+	}
+	return merge(append(exits, c.flow)...)
+}
+
+// exits reports whether a block ends with a statement that leaves it
+func exits(block *ast.BlockStatement) bool {
+	if len(block.Statements) == 0 {
+		return false
+	}
+
+	switch block.Statements[len(block.Statements)-1].(type) {
+	case *ast.ReturnStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,669 @@
+// Package typecheck checks the types of a program before it runs. Types are
+// inferred from literals, annotations and the signatures of functions, and an
+// expression whose type cannot be known, such as an unannotated parameter or
+// an imported name, is accepted anywhere, so that the checker only reports
+// code that would fail at runtime.
+package typecheck
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onurravli/goception/ast"
+)
+
+// Error is a type error and the position of the code that causes it
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Check checks a program and returns every type error found, in source order.
+// Predeclared names are defined outside the program, for example by the host
+// or by earlier lines of a REPL session; their types are unknown.
+func Check(program *ast.Program, predeclared ...string) []*Error {
+	errors, _ := CheckImports(program, predeclared...)
+	return errors
+}
+
+// CheckImports checks a program like Check and also returns its import
+// statements in source order, so that the files they import can be checked
+// in turn
+func CheckImports(program *ast.Program, predeclared ...string) ([]*Error, []*ast.ImportStatement) {
+	c := &checker{scope: newScope(nil), flow: state{}, declarations: make(map[*ast.Identifier]*binding),
+		elements: make(map[ast.Expression]*Type), imported: make(map[*ast.ImportStatement]bool)}
+	for _, name := range predeclared {
+		c.scope.declare(name, &binding{declared: Unknown, assumed: Unknown})
+	}
+
+	c.checkStatements(program.Statements)
+	return c.errors, c.imports
+}
+
+// scope holds the names defined in a function, a for loop or the program
+// itself. Like in the evaluator, other blocks share the scope they are in.
+type scope struct {
+	bindings map[string]*binding
+	outer    *scope
+}
+
+// binding is a name defined in a scope. Its declared type is the type of the
+// values it may be assigned; the type of its current value may be narrower,
+// for example not null after a null check, and is tracked by the checker's
+// flow state.
+type binding struct {
+	declared *Type
+	constant bool
+
+	// assumed is the type of the value when nothing is known of it, such as
+	// in a function that reads a variable of an enclosing scope
+	assumed *Type
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{bindings: make(map[string]*binding), outer: outer}
+}
+
+func (s *scope) declare(name string, b *binding) {
+	s.bindings[name] = b
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	b, ok := s.bindings[name]
+	if !ok && s.outer != nil {
+		return s.outer.lookup(name)
+	}
+	return b, ok
+}
+
+type checker struct {
+	errors []*Error
+	scope  *scope
+
+	// flow holds the type of the current value of the bindings narrowed since
+	// the start of the function being checked
+	flow state
+
+	// functions holds what is known of the results of each enclosing function
+	functions []*function
+
+	// loops holds the states in which each enclosing loop is left or continued
+	loops []*[]state
+
+	// declarations holds the binding of each var and const statement, which
+	// stays the same when a loop body is checked again
+	declarations map[*ast.Identifier]*binding
+
+	// elements holds the types of the elements, keys and values of array and
+	// map literals, to point to the one that does not have the type expected
+	elements map[ast.Expression]*Type
+
+	// imports holds the import statements of the program, each listed once
+	// although a loop body is checked more than once
+	imports  []*ast.ImportStatement
+	imported map[*ast.ImportStatement]bool
+}
+
+// function holds the declared return type of a function being checked and the
+// types of the values its return statements return
+type function struct {
+	declared *Type
+	results  []*Type
+}
+
+// errorf records a type error at the position of node
+func (c *checker) errorf(node ast.Node, format string, a ...interface{}) {
+	line, column := node.Pos()
+	c.errors = append(c.errors, &Error{Line: line, Column: column, Message: fmt.Sprintf(format, a...)})
+}
+
+// checkStatements checks the statements of a scope. Functions are declared
+// before any statement is checked, so that they can call each other and
+// themselves whatever order they are defined in.
+func (c *checker) checkStatements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.VarStatement:
+			if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+				t := functionType(fn)
+				c.scope.declare(stmt.Name.Value, &binding{declared: t, assumed: t})
+			}
+		case *ast.ConstStatement:
+			if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+				t := functionType(fn)
+				c.scope.declare(stmt.Name.Value, &binding{declared: t, assumed: t, constant: true})
+			}
+		}
+	}
+
+	for _, stmt := range statements {
+		c.checkStatement(stmt)
+	}
+}
+
+func (c *checker) checkStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.VarStatement:
+		c.checkDeclaration(stmt.Name, stmt.Type, stmt.Value, false)
+	case *ast.ConstStatement:
+		c.checkDeclaration(stmt.Name, stmt.Type, stmt.Value, true)
+	case *ast.ReturnStatement:
+		t := c.checkExpression(stmt.ReturnValue)
+		if len(c.functions) > 0 {
+			fn := c.functions[len(c.functions)-1]
+			if !assignable(fn.declared, t) {
+				node, detail := c.mismatch(stmt.ReturnValue, fn.declared)
+				c.errorf(node, "return type mismatch: expected %s, got %s%s", fn.declared, t.value(), detail)
+			}
+			fn.results = append(fn.results, t)
+		}
+	case *ast.ExpressionStatement:
+		if stmt.Expression != nil {
+			c.checkExpression(stmt.Expression)
+		}
+	case *ast.BlockStatement:
+		c.checkStatements(stmt.Statements)
+	case *ast.WhileStatement:
+		c.checkLoop(stmt.Condition, stmt.Body, nil)
+	case *ast.ForStatement:
+		outer := c.scope
+		c.scope = newScope(outer)
+		if stmt.Init != nil {
+			c.checkStatement(stmt.Init)
+		}
+		c.checkLoop(stmt.Condition, stmt.Body, stmt.Update)
+		c.scope = outer
+	case *ast.BreakStatement, *ast.ContinueStatement:
+		if len(c.loops) > 0 {
+			exits := c.loops[len(c.loops)-1]
+			*exits = append(*exits, c.flow.copy())
+		}
+	case *ast.ImportStatement:
+		// Imported files are checked on their own, so what they define is unknown here
+		if !c.imported[stmt] {
+			c.imported[stmt] = true
+			c.imports = append(c.imports, stmt)
+		}
+		if stmt.Alias != nil {
+			c.scope.declare(stmt.Alias.Value, &binding{declared: Module, assumed: Module, constant: true})
+		}
+		for _, name := range stmt.Names {
+			c.scope.declare(name.Value, &binding{declared: Unknown, assumed: Unknown})
+		}
+	}
+}
+
+// checkDeclaration checks the value of a var or const statement against its
+// annotation and declares the name. A name without an annotation takes the
+// type of its value, and values later assigned to a variable must have that
+// type or be null.
+func (c *checker) checkDeclaration(name *ast.Identifier, annotation *ast.TypeAnnotation, value ast.Expression, constant bool) {
+	t := c.checkExpression(value)
+
+	b, ok := c.declarations[name]
+	if !ok {
+		b = &binding{}
+		c.declarations[name] = b
+	}
+
+	b.constant = constant
+	if annotation != nil {
+		b.declared = fromAnnotation(annotation)
+		if !assignable(b.declared, t) {
+			node, detail := c.mismatch(value, b.declared)
+			c.errorf(node, "type mismatch: expected %s, got %s%s", b.declared, t.value(), detail)
+		}
+
+		b.assumed = b.declared
+		if b.declared.is(Function) && b.declared.Signature == nil && t.is(Function) {
+			// A function keeps the signature of its value, which the annotation does not describe
+			b.assumed = t
+		}
+	} else {
+		// Like at runtime, a variable whose first value is null has no type, and
+		// any array, map or function may replace an array, map or function
+		b.assumed = t
+		if t.is(Null) {
+			b.assumed = Unknown
+		}
+		b.declared = b.assumed
+		if t.Args != nil || t.Signature != nil {
+			b.declared = &Type{Name: t.Name, Object: t.Object}
+		}
+		if b.declared.known() || b.declared.Options != nil {
+			b.declared = unionOf(b.declared, Null)
+		}
+	}
+
+	c.scope.declare(name.Value, b)
+	c.flow[b] = b.narrow(t)
+}
+
+// checkExpression checks an expression and returns its type. An expression
+// with a type error has an unknown type, so one mistake is reported once.
+func (c *checker) checkExpression(expr ast.Expression) *Type {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.StringLiteral:
+		return String
+	case *ast.CharLiteral:
+		return Char
+	case *ast.Boolean, *ast.BooleanLiteral:
+		return Bool
+	case *ast.NullLiteral:
+		return Null
+	case *ast.ArrayLiteral:
+		if len(expr.Elements) == 0 {
+			return Array
+		}
+		elements := make([]*Type, len(expr.Elements))
+		for i, element := range expr.Elements {
+			elements[i] = c.checkElement(element)
+		}
+		return collectionOf(Array, unionOf(elements...))
+	case *ast.HashLiteral:
+		if len(expr.Pairs) == 0 {
+			return Map
+		}
+		keys := make([]*Type, len(expr.Pairs))
+		values := make([]*Type, len(expr.Pairs))
+		for i, pair := range expr.Pairs {
+			keys[i] = c.checkElement(pair.Key)
+			values[i] = c.checkElement(pair.Value)
+		}
+		return collectionOf(Map, unionOf(keys...), unionOf(values...))
+	case *ast.Identifier:
+		if b, ok := c.scope.lookup(expr.Value); ok {
+			return c.flow.read(b)
+		}
+		return Unknown
+	case *ast.PrefixExpression:
+		return c.checkPrefixExpression(expr)
+	case *ast.InfixExpression:
+		return c.checkInfixExpression(expr)
+	case *ast.IfExpression:
+		c.checkIfExpression(expr)
+		return Unknown
+	case *ast.FunctionLiteral:
+		return c.checkFunctionLiteral(expr)
+	case *ast.CallExpression:
+		return c.checkCallExpression(expr)
+	case *ast.IndexExpression:
+		return c.checkIndexExpression(expr)
+	case *ast.MemberExpression:
+		t := c.checkExpression(expr.Object)
+		if t.known() && !t.is(Module) {
+			c.errorf(expr, "member access not supported: %s.%s", t.value(), expr.Property.Value)
+		}
+		return Unknown
+	case *ast.AssignmentExpression:
+		// Assigning to a constant fails at runtime whatever the type of the value
+		t := c.checkExpression(expr.Value)
+		b, ok := c.scope.lookup(expr.Name.Value)
+		if !ok || b.constant {
+			return t
+		}
+		if !assignable(b.declared, t) {
+			node, detail := c.mismatch(expr.Value, b.declared)
+			c.errorf(node, "type mismatch in assignment to %s: expected %s, got %s%s",
+				expr.Name.Value, b.declared, t.value(), detail)
+			c.flow[b] = b.assumed
+			return Unknown
+		}
+		c.flow[b] = b.narrow(t)
+		return t
+	case *ast.IndexAssignmentExpression:
+		return c.checkIndexAssignment(expr)
+	default:
+		return Unknown
+	}
+}
+
+func (c *checker) checkPrefixExpression(expr *ast.PrefixExpression) *Type {
+	right := c.checkExpression(expr.Right)
+
+	switch expr.Operator {
+	case "!":
+		return Bool
+	case "-":
+		right = c.checkNotNull(expr.Right, right, expr.Operator)
+		if !right.known() || right.numeric() {
+			return right
+		}
+	}
+
+	c.errorf(expr, "unknown operator: %s%s", expr.Operator, right.value())
+	return Unknown
+}
+
+// checkInfixExpression follows the rules of the evaluator's operators
+func (c *checker) checkInfixExpression(expr *ast.InfixExpression) *Type {
+	if expr.Operator == "&&" || expr.Operator == "||" {
+		c.checkLogicalExpression(expr)
+		return Bool
+	}
+
+	left := c.checkExpression(expr.Left)
+	right := c.checkExpression(expr.Right)
+	op := expr.Operator
+
+	ordering := op == "<" || op == ">" || op == "<=" || op == ">="
+	comparison := ordering || op == "==" || op == "!="
+	arithmetic := op == "+" || op == "-" || op == "*" || op == "/" || op == "%"
+
+	// Any value may be compared to null or concatenated to a string
+	if (arithmetic || ordering) && !(op == "+" && (left.is(String) || right.is(String))) {
+		left = c.checkNotNull(expr.Left, left, op)
+		right = c.checkNotNull(expr.Right, right, op)
+	}
+
+	switch {
+	case op == "+" && (left.is(String) || right.is(String)):
+		return String
+	case !left.known() || !right.known():
+		if comparison {
+			return Bool
+		}
+		return Unknown
+	case left.is(Int) && right.is(Int) && arithmetic:
+		return Int
+	case left.numeric() && right.numeric() && arithmetic:
+		return Float
+	case left.numeric() && right.numeric() && comparison:
+		return Bool
+	case left.is(Char) && right.is(Char) && comparison:
+		return Bool
+	case left.is(String) && right.is(String):
+		// Strings only support concatenation, handled above
+	case op == "==" || op == "!=":
+		return Bool
+	case left.Name != right.Name:
+		c.errorf(expr, "type mismatch: %s %s %s", left.value(), op, right.value())
+		return Unknown
+	}
+
+	c.errorf(expr, "unknown operator: %s %s %s", left.value(), op, right.value())
+	return Unknown
+}
+
+// checkFunctionLiteral checks the body of a function in a scope of its own,
+// with its parameters declared, and returns its type. The return type of a
+// function without an annotation is inferred from its body.
+func (c *checker) checkFunctionLiteral(fn *ast.FunctionLiteral) *Type {
+	t := functionType(fn)
+
+	// The function may be called whenever the variables it reads have any of
+	// their values, so nothing known of their current values applies in it
+	outer, outerFlow, outerLoops := c.scope, c.flow, c.loops
+	c.scope, c.flow, c.loops = newScope(outer), state{}, nil
+	for i, param := range fn.Parameters {
+		c.scope.declare(param.Name, &binding{declared: t.Signature.Params[i], assumed: t.Signature.Params[i]})
+	}
+	c.functions = append(c.functions, &function{declared: t.Signature.Return})
+
+	c.checkStatements(fn.Body.Statements)
+
+	results := c.functions[len(c.functions)-1].results
+	c.functions = c.functions[:len(c.functions)-1]
+	c.scope, c.flow, c.loops = outer, outerFlow, outerLoops
+
+	if fn.ReturnType == nil {
+		t.Signature.Return = inferReturn(fn.Body, results)
+	}
+	return t
+}
+
+// inferReturn returns the type of the values a function returns, if the type
+// of the value of every return statement is known. A function that does not
+// end with a return statement may return the value of its last statement or
+// null, so its return type is unknown.
+func inferReturn(body *ast.BlockStatement, results []*Type) *Type {
+	if len(body.Statements) == 0 || len(results) == 0 {
+		return Unknown
+	}
+	if _, ok := body.Statements[len(body.Statements)-1].(*ast.ReturnStatement); !ok {
+		return Unknown
+	}
+
+	for _, t := range results {
+		if !t.known() && t.Options == nil {
+			return Unknown
+		}
+	}
+	return unionOf(results...)
+}
+
+func (c *checker) checkCallExpression(call *ast.CallExpression) *Type {
+	args := make([]*Type, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		args[i] = c.checkExpression(arg)
+	}
+
+	// A builtin, unless the name is defined by the program
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		if _, defined := c.scope.lookup(ident.Value); !defined {
+			if b, ok := builtins[ident.Value]; ok {
+				return c.checkBuiltinCall(call, ident.Value, b, args)
+			}
+			return Unknown
+		}
+	}
+
+	fn := c.checkExpression(call.Function)
+	if !fn.known() {
+		return Unknown
+	}
+	if !fn.is(Function) {
+		c.errorf(call, "not a function: %s", fn.value())
+		return Unknown
+	}
+	if fn.Signature == nil {
+		return Unknown
+	}
+
+	params := fn.Signature.Params
+	if len(args) != len(params) {
+		c.errorf(call, "wrong number of arguments: expected %d, got %d", len(params), len(args))
+	}
+	for i := 0; i < len(args) && i < len(params); i++ {
+		if !assignable(params[i], args[i]) {
+			node, detail := c.mismatch(call.Arguments[i], params[i])
+			c.errorf(node, "type mismatch for argument %d: expected %s, got %s%s", i, params[i], args[i].value(), detail)
+		}
+	}
+
+	return fn.Signature.Return
+}
+
+func (c *checker) checkBuiltinCall(call *ast.CallExpression, name string, b builtin, args []*Type) *Type {
+	if len(args) == 0 || !args[0].known() {
+		return b.result
+	}
+
+	for _, t := range b.first {
+		if args[0].is(t) {
+			if name == "push" && args[0].Args != nil {
+				c.checkPushedElements(call, args[0].Args[0], args[1:])
+			}
+			return b.result
+		}
+	}
+
+	if len(b.first) > 1 {
+		c.errorf(call.Arguments[0], "argument to `%s` not supported, got %s", name, args[0].value())
+	} else {
+		c.errorf(call.Arguments[0], "argument to `%s` must be %s, got %s", name, b.first[0].value(), args[0].value())
+	}
+	return b.result
+}
+
+// checkPushedElements checks that the values pushed to an array have the type
+// of its elements
+func (c *checker) checkPushedElements(call *ast.CallExpression, element *Type, values []*Type) {
+	for i, value := range values {
+		if !assignable(element, value) {
+			node, detail := c.mismatch(call.Arguments[i+1], element)
+			c.errorf(node, "type mismatch for argument %d: expected %s, got %s%s", i+1, element, value.value(), detail)
+		}
+	}
+}
+
+func (c *checker) checkIndexExpression(expr *ast.IndexExpression) *Type {
+	left := c.checkExpression(expr.Left)
+	index := c.checkExpression(expr.Index)
+	return c.checkIndex(expr, left, index)
+}
+
+// checkIndex checks indexing a value of type left with a value of type index
+// and returns the type of the element. Looking up a missing key of a map gives
+// null.
+func (c *checker) checkIndex(expr *ast.IndexExpression, left, index *Type) *Type {
+	switch {
+	case !left.known():
+		return Unknown
+	case left.is(Map):
+		if left.Args == nil {
+			return Unknown
+		}
+		return unionOf(left.Args[1], Null)
+	case (left.is(Array) || left.is(String)) && (!index.known() || index.is(Int)):
+		if left.is(String) {
+			return Char
+		}
+		if left.Args == nil {
+			return Unknown
+		}
+		return left.Args[0]
+	}
+
+	c.errorf(expr, "index operator not supported: %s[%s]", left.value(), index.value())
+	return Unknown
+}
+
+// checkIndexAssignment checks an assignment to an element of an array or map,
+// whose key and value must have the types of its contents
+func (c *checker) checkIndexAssignment(expr *ast.IndexAssignmentExpression) *Type {
+	left := c.checkExpression(expr.Target.Left)
+	index := c.checkExpression(expr.Target.Index)
+	c.checkIndex(expr.Target, left, index)
+	value := c.checkExpression(expr.Value)
+
+	if !left.known() || left.Args == nil {
+		return value
+	}
+	target := ast.Source(expr.Target)
+	if left.is(Map) && !assignable(left.Args[0], index) {
+		node, detail := c.mismatch(expr.Target.Index, left.Args[0])
+		c.errorf(node, "type mismatch for key in assignment to %s: expected %s, got %s%s", target, left.Args[0], index.value(), detail)
+	}
+	element := left.Args[len(left.Args)-1]
+	if (left.is(Array) || left.is(Map)) && !assignable(element, value) {
+		node, detail := c.mismatch(expr.Value, element)
+		c.errorf(node, "type mismatch in assignment to %s: expected %s, got %s%s", target, element, value.value(), detail)
+	}
+	return value
+}
+
+// checkElement checks an element, key or value of an array or map literal and
+// records its type
+func (c *checker) checkElement(expr ast.Expression) *Type {
+	t := c.checkExpression(expr)
+	c.elements[expr] = t
+	return t
+}
+
+// mismatch returns where to report that value, of type t, does not have the
+// type expected, and the details to add to the error. For an array or map
+// literal, that is the first element that does not have the type expected
+// for its contents, with the path of indexes and keys leading to it - e.g.,
+// " (at key a, index 2: expected int, got STRING)"; for other values, it is the
+// value itself.
+func (c *checker) mismatch(value ast.Expression, expected *Type) (ast.Node, string) {
+	path, node, want, got := c.findElementMismatch(value, expected)
+	if path == nil {
+		return value, ""
+	}
+	return node, fmt.Sprintf(" (at %s: expected %s, got %s)", strings.Join(path, ", "), want, got.value())
+}
+
+// findElementMismatch returns the path to the first element of a literal that
+// does not have its type in expected, the element, its expected type and its type
+func (c *checker) findElementMismatch(value ast.Expression, expected *Type) ([]string, ast.Node, *Type, *Type) {
+	if expected.Options != nil {
+		// Describe the contents of the literal against the first option of its kind
+		for _, option := range expected.Options {
+			if literalOf(value, option) {
+				return c.findElementMismatch(value, option)
+			}
+		}
+		return nil, nil, nil, nil
+	}
+	if expected.Args == nil || !literalOf(value, expected) {
+		return nil, nil, nil, nil
+	}
+
+	// element returns the path to an element that does not have type want
+	element := func(step string, expr ast.Expression, want *Type) ([]string, ast.Node, *Type, *Type) {
+		got := c.elements[expr]
+		if got == nil || assignable(want, got) {
+			return nil, nil, nil, nil
+		}
+		if path, node, inner, innerGot := c.findElementMismatch(expr, want); path != nil {
+			return append([]string{step}, path...), node, inner, innerGot
+		}
+		return []string{step}, expr, want, got
+	}
+
+	switch value := value.(type) {
+	case *ast.ArrayLiteral:
+		for i, expr := range value.Elements {
+			if path, node, want, got := element(fmt.Sprintf("index %d", i), expr, expected.Args[0]); path != nil {
+				return path, node, want, got
+			}
+		}
+	case *ast.HashLiteral:
+		for _, pair := range value.Pairs {
+			step := "key " + pair.Key.String()
+			if path, node, want, got := element(step, pair.Key, expected.Args[0]); path != nil {
+				return path, node, want, got
+			}
+			if path, node, want, got := element(step, pair.Value, expected.Args[1]); path != nil {
+				return path, node, want, got
+			}
+		}
+	}
+	return nil, nil, nil, nil
+}
+
+// literalOf reports whether value is an array or map literal of the kind t is
+func literalOf(value ast.Expression, t *Type) bool {
+	switch value.(type) {
+	case *ast.ArrayLiteral:
+		return t.is(Array)
+	case *ast.HashLiteral:
+		return t.is(Map)
+	}
+	return false
+}
+
+// functionType returns the type of a function literal from its annotations
+func functionType(fn *ast.FunctionLiteral) *Type {
+	params := make([]*Type, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = fromAnnotation(param.Type)
+	}
+
+	return &Type{
+		Name:      Function.Name,
+		Object:    Function.Object,
+		Signature: &Signature{Params: params, Return: fromAnnotation(fn.ReturnType)},
+	}
+}
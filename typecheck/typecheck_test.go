@@ -0,0 +1,260 @@
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/onurravli/goception/lexer"
+	"github.com/onurravli/goception/parser"
+)
+
+func check(t *testing.T, input string, predeclared ...string) []*Error {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse errors in %q: %v", input, p.Errors())
+	}
+	return Check(program, predeclared...)
+}
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`var age: int = "twenty";`, []string{"line 1, column 16: type mismatch: expected int, got STRING"}},
+		{`const ratio: float = 1;`, []string{"line 1, column 22: type mismatch: expected float, got INTEGER"}},
+		{`1 + true;`, []string{"line 1, column 3: type mismatch: INTEGER + BOOLEAN"}},
+		{`"a" - "b";`, []string{"line 1, column 5: unknown operator: STRING - STRING"}},
+		{`-"a";`, []string{"line 1, column 1: unknown operator: -STRING"}},
+		{`const s = "abc"; var c: int = s[0];`, []string{"line 1, column 32: type mismatch: expected int, got CHAR"}},
+		{`5[0];`, []string{"line 1, column 2: index operator not supported: INTEGER[INTEGER]"}},
+		{`const n = 5; n.x;`, []string{"line 1, column 15: member access not supported: INTEGER.x"}},
+		{`const n = 5; n(1);`, []string{"line 1, column 14: not a function: INTEGER"}},
+		{`push(5, 1);`, []string{"line 1, column 6: argument to `push` must be ARRAY, got INTEGER"}},
+		{`len(5);`, []string{"line 1, column 5: argument to `len` not supported, got INTEGER"}},
+		{`var n: string = len("abc");`, []string{"line 1, column 17: type mismatch: expected string, got INTEGER"}},
+		{
+			`const add = function(a: int, b: int): int { return a + b; };
+			add(1, "2");
+			add(1);
+			var s: string = add(1, 2);`,
+			[]string{
+				"line 2, column 11: type mismatch for argument 1: expected int, got STRING",
+				"line 3, column 4: wrong number of arguments: expected 2, got 1",
+				"line 4, column 20: type mismatch: expected string, got INTEGER",
+			},
+		},
+		{
+			// Errors in branches that rarely run are found too
+			`const f = function(x): string {
+				if (x > 100) { return 2; }
+				while (false) { var b: bool = 1; }
+				return "small";
+			};`,
+			[]string{
+				"line 2, column 27: return type mismatch: expected string, got INTEGER",
+				"line 3, column 35: type mismatch: expected bool, got INTEGER",
+			},
+		},
+		{
+			// Functions may call functions defined after them
+			`const even = function(n: int): bool { if (n == 0) { return true; } return odd(n - 1); };
+			const odd = function(n: int): bool { if (n == 0) { return false; } return even(n - 1); };
+			odd("3");`,
+			[]string{"line 3, column 8: type mismatch for argument 0: expected int, got STRING"},
+		},
+		{
+			`for (var i: int = 0; i < 3; i = i + 1) { const label: string = i + 1; }`,
+			[]string{"line 1, column 66: type mismatch: expected string, got INTEGER"},
+		},
+		{
+			// Unannotated bindings keep the type of their initial value
+			`var x = 1; x = "now a string"; var s: string = x;`,
+			[]string{
+				"line 1, column 16: type mismatch in assignment to x: expected int?, got STRING",
+				"line 1, column 48: type mismatch: expected string, got INTEGER",
+			},
+		},
+		{
+			// Return types are inferred from the body of a function
+			`const sum = function(n: int) { var total = 0; for (var i = 1; i <= n; i = i + 1) { total = total + i; } return total; };
+			var total = sum(10);
+			total = "oops";`,
+			[]string{"line 3, column 12: type mismatch in assignment to total: expected int?, got STRING"},
+		},
+		{
+			// Function types describe the parameters and result of a function
+			`const apply = function(f: (int) -> int, x: int): int { return f(x); };
+			const greet = function(name: string): string { return "Hi " + name; };
+			apply(greet, 1);
+			apply(function(a: int, b: int): int { return a + b; }, 1);
+			const run = function(f: (int) -> int): string { return f(1); };
+			const call = function(f: (int) -> int) { f("one"); };`,
+			[]string{
+				"line 3, column 10: type mismatch for argument 0: expected (int) -> int, got FUNCTION",
+				"line 4, column 10: type mismatch for argument 0: expected (int) -> int, got FUNCTION",
+				"line 5, column 59: return type mismatch: expected string, got INTEGER",
+				"line 6, column 47: type mismatch for argument 0: expected int, got STRING",
+			},
+		},
+		{
+			`var n: int = null;
+			var u: int | string = 1;
+			u = true;
+			const inc = function(n: int): int { return n + 1; };
+			const f = function(x: int?) { return inc(x); };`,
+			[]string{
+				"line 1, column 14: type mismatch: expected int, got NULL",
+				"line 3, column 8: type mismatch in assignment to u: expected int | string, got BOOLEAN",
+				"line 5, column 45: type mismatch for argument 0: expected int, got INTEGER | NULL",
+			},
+		},
+		{
+			// Nullable values must be checked against null before arithmetic
+			`const f = function(x: int?): int { return x + 1; };
+			const g = function(x: int?) { return -x; };
+			var y: float? = 1.5;
+			y = null;
+			y * 2;`,
+			[]string{
+				"line 1, column 43: x may be null: check that it is not null before using +",
+				"line 2, column 42: x may be null: check that it is not null before using -",
+				"line 5, column 6: type mismatch: NULL * INTEGER",
+			},
+		},
+		{
+			// A check only holds until the variable is assigned again, in any iteration of a loop
+			`const next = function(): int? { return null; };
+			const f = function(x: int?) {
+				if (x != null) {
+					while (true) { x = x + 1; if (x > 10) { x = next(); } }
+				}
+			};`,
+			[]string{"line 4, column 25: x may be null: check that it is not null before using +"},
+		},
+		{
+			// The result of a function that may return null is nullable
+			`const find = function(n: int) { if (n > 0) { return n; } return null; };
+			var found = find(1);
+			found + 1;`,
+			[]string{"line 3, column 4: found may be null: check that it is not null before using +"},
+		},
+		{
+			// Errors point to the element of a literal that does not have the type expected
+			`var xs: array<int> = [1, "two", 3];
+			var m: map<string, array<bool>> = {"a": [true], "b": [false, 1]};
+			const count = function(names: array<string>): int { return len(names); };
+			count(["a", 'b']);
+			var ages: map<string, int> = {"ada": 36};
+			ages = {1: 2};
+			const nums = function(): array<int> { return [1, 2]; };
+			var names: array<string> = nums();`,
+			[]string{
+				"line 1, column 26: type mismatch: expected array<int>, got ARRAY (at index 1: expected int, got STRING)",
+				"line 2, column 65: type mismatch: expected map<string, array<bool>>, got HASH (at key b, index 1: expected bool, got INTEGER)",
+				"line 4, column 16: type mismatch for argument 0: expected array<string>, got ARRAY (at index 1: expected string, got CHAR)",
+				"line 6, column 12: type mismatch in assignment to ages: expected map<string, int>, got HASH (at key 1: expected string, got INTEGER)",
+				"line 8, column 31: type mismatch: expected array<string>, got ARRAY",
+			},
+		},
+		{
+			// Elements changed or read must have the types declared for the contents
+			`var arr: array<int> = [1];
+			arr[0] = "x";
+			push(arr, 2, "x");
+			var m: map<string, int> = {"a": 1};
+			m["b"] = "no";
+			m[1] = 2;
+			var s: string = arr[0];
+			m["a"] + 1;
+			var grid: map<string, array<int>> = {};
+			grid["row"] = [1, true];`,
+			[]string{
+				"line 2, column 13: type mismatch in assignment to arr[0]: expected int, got STRING",
+				"line 3, column 17: type mismatch for argument 2: expected int, got STRING",
+				"line 5, column 13: type mismatch in assignment to m[\"b\"]: expected int, got STRING",
+				"line 6, column 6: type mismatch for key in assignment to m[1]: expected string, got INTEGER",
+				"line 7, column 23: type mismatch: expected string, got INTEGER",
+				"line 8, column 5: m[\"a\"] may be null: check that it is not null before using +",
+				"line 10, column 22: type mismatch in assignment to grid[\"row\"]: expected array<int>, got ARRAY (at index 1: expected int, got BOOLEAN)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		errors := check(t, tt.input)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%s: expected %d errors, got %d: %v", tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != tt.expected[i] {
+				t.Errorf("%s: wrong error. expected=%q, got=%q", tt.input, tt.expected[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestValidPrograms(t *testing.T) {
+	tests := []string{
+		// Unknown types are accepted anywhere
+		`const double = function(x) { return x * 2; }; var n: int = double(2); var s: string = double("a");`,
+		`import "math-utils" as m; var n: int = m.square(2); import { PI } from "math-utils"; var f: float = PI;`,
+		`var x = first([]); x = "a string"; x = 1;`,
+		// Null checks narrow nullable values
+		`const f = function(x: int?): int { if (x != null) { return x + 1; } return 0; };`,
+		`const f = function(x: int?): int { if (x == null) { return 0; } return x * 2; };`,
+		`const f = function(x: int?, y: float?) { return x != null && y != null && x < y; };`,
+		`const f = function(x: int?) { return x == null || x > 1; };`,
+		`const f = function(x: int?) { if (!(x == null)) { x - 1; } else { x = 1; } return x + 1; };`,
+		`const f = function(x: string?) { while (x != null) { print(x + 1); x = null; } return "x: " + x; };`,
+		`var n: int? = 1; n + 1; n = null; n = 2; n * 3;`,
+		// A variable that is null can only be not null after it is assigned
+		`var n: int? = null; if (n == null) { n = 3; } print(n + 1); var s: string? = null; if (s != null) { s + "!"; }`,
+		`var u: int | string = 1; u = "a"; var s: string | null = null; s = "b"; s = null;`,
+		// Empty literals and values of unknown type may have any contents
+		`var xs: array<int> = []; xs = [1, 2]; var m: map<string, array<int>> = {"a": [1], "b": []}; m = {"c": first([])};`,
+		`var ys: array<int?> = [1, null]; var u: array<int> | string = "s"; u = [1];`,
+		// A variable without an annotation may be assigned an array with other contents
+		`var xs = [1, 2]; xs = ["a"];`,
+		// The contents of an array or map declared without them may be changed to anything
+		`var a: array = [1]; a[0] = "x"; var s: string = a[0]; var b = [1]; push(b, "x"); b[1] = true;`,
+		`var m: map<string, array<int>> = {"a": [1]}; m["a"][0] = 2; push(m["a"], 3); var n: int = m["a"][1]; var v = m["b"]; if (v != null) { v[0] + 1; }`,
+		// A loop that nests a collection deeper in each iteration is checked in a few passes
+		`var a = [1]; var i = 0; while (i < 3) { a = [a]; i = i + 1; } print(len(a));`,
+		`var m = {"a": 1}; for (var i = 0; i < 3; i = i + 1) { m = {"n": [m]}; } print(m);`,
+		// Variables of enclosing scopes are not null in functions, unless declared nullable
+		`var count = 0; const inc = function() { count = count + 1; return count; };`,
+		`var name = null; const greet = function() { return name + 1; }; name = 1;`,
+		// A function that may fall off its end has an unknown return type
+		`const f = function(x) { if (x) { return 1; } "none"; }; var r = f(true); r = "a string";`,
+		`const f = function(x) { if (x) { return 1; } return "one"; }; var r = f(true); r = "a string";`,
+		`import "math-utils" as m; const n = 1; n = "a"; m = 2;`,
+		// Mixed arithmetic, concatenation and comparisons
+		`const f: float = 1 + 2.5; const s: string = "n = " + 1; const b: bool = 'a' < 'b' && 1 < 2.5;`,
+		`const name: string = "Ada"; const c: char = name[0]; const n: int = len(name) + ord(c);`,
+		`const h = {"a": [1, 2]}; var first = h["a"][0]; h["b"] = push(h["a"], 3);`,
+		// A recursive function sees its own signature
+		`const fact = function(n: int): int { if (n <= 1) { return 1; } return n * fact(n - 1); }; fact(5);`,
+		// A function annotated only as a function keeps the signature of its value
+		`const apply = function(f: function, x: int): int { return f(x); }; const inc: function = function(x: int): int { return x + 1; }; apply(inc, 1);`,
+		// Unannotated parameters and results match any type in a signature
+		`const apply = function(f: (int) -> int, x: int): int { return f(x); }; apply(function(x) { return x * 2; }, 1); apply(function(x: int) { return x; }, 2);`,
+		`var f: (int, int) -> int = function(a: int, b: int): int { return a + b; }; f = function(a, b) { return a - b; };`,
+	}
+
+	for _, input := range tests {
+		if errors := check(t, input); len(errors) != 0 {
+			t.Errorf("%s: unexpected errors: %v", input, errors)
+		}
+	}
+}
+
+func TestPredeclaredNames(t *testing.T) {
+	// A name defined by the host hides the builtin of the same name
+	if errors := check(t, `var s: string = len("abc");`, "len"); len(errors) != 0 {
+		t.Errorf("unexpected errors: %v", errors)
+	}
+}
@@ -0,0 +1,342 @@
+package typecheck
+
+import (
+	"strings"
+
+	"github.com/onurravli/goception/ast"
+	"github.com/onurravli/goception/object"
+)
+
+// Type is the static type of an expression
+type Type struct {
+	Name      string            // int, float, bool, string, char, array, map, function, module or null; empty when unknown or a union
+	Object    object.ObjectType // the type of the values at runtime, used to describe values in errors like the evaluator does
+	Args      []*Type           // the element type of an array, or the key and value types of a map, if known
+	Signature *Signature        // the parameters and result of a function, if known
+	Options   []*Type           // the types of the values of a union
+}
+
+// Signature describes the parameters and result of a function
+type Signature struct {
+	Params []*Type
+	Return *Type
+}
+
+// Types known to the checker. A value of unknown type, such as an unannotated
+// parameter, is accepted wherever a value is expected.
+var (
+	Unknown  = &Type{}
+	Int      = &Type{Name: "int", Object: object.INTEGER_OBJ}
+	Float    = &Type{Name: "float", Object: object.FLOAT_OBJ}
+	Bool     = &Type{Name: "bool", Object: object.BOOLEAN_OBJ}
+	String   = &Type{Name: "string", Object: object.STRING_OBJ}
+	Char     = &Type{Name: "char", Object: object.CHAR_OBJ}
+	Array    = &Type{Name: "array", Object: object.ARRAY_OBJ}
+	Map      = &Type{Name: "map", Object: object.HASH_OBJ}
+	Function = &Type{Name: "function", Object: object.FUNCTION_OBJ}
+	Module   = &Type{Name: "module", Object: object.MODULE_OBJ}
+	Null     = &Type{Name: "null", Object: object.NULL_OBJ}
+)
+
+// String returns the type as it is written in annotations
+func (t *Type) String() string {
+	if t.Options != nil {
+		options := []string{}
+		for _, option := range t.Options {
+			if option.Signature != nil {
+				options = append(options, "("+option.String()+")")
+			} else {
+				options = append(options, option.String())
+			}
+		}
+		if len(options) == 2 && options[1] == Null.Name {
+			return options[0] + "?"
+		}
+		return strings.Join(options, " | ")
+	}
+	if t.Name == "" {
+		return "unknown"
+	}
+	if t.Args != nil {
+		args := []string{}
+		for _, arg := range t.Args {
+			args = append(args, arg.String())
+		}
+		return t.Name + "<" + strings.Join(args, ", ") + ">"
+	}
+	if t.Signature == nil {
+		return t.Name
+	}
+
+	params := []string{}
+	for _, param := range t.Signature.Params {
+		params = append(params, param.String())
+	}
+	return "(" + strings.Join(params, ", ") + ") -> " + t.Signature.Return.String()
+}
+
+// value describes a value of the type, as the evaluator does in its errors
+func (t *Type) value() string {
+	if t.Options != nil {
+		options := []string{}
+		for _, option := range t.Options {
+			options = append(options, option.value())
+		}
+		return strings.Join(options, " | ")
+	}
+	if t.Object == "" {
+		return "unknown"
+	}
+	return string(t.Object)
+}
+
+// is reports whether t is the known type other, ignoring signatures
+func (t *Type) is(other *Type) bool {
+	return t.Name != "" && t.Name == other.Name
+}
+
+// known reports whether the type of a value is known and not a union.
+// Operators accept values of a union like values of unknown type.
+func (t *Type) known() bool {
+	return t.Name != ""
+}
+
+// nullable reports whether t is a union that includes null, whose values must
+// be checked against null before they are used like values of the other types
+func (t *Type) nullable() bool {
+	for _, option := range t.Options {
+		if option.is(Null) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutNull returns the type of the values of t that are not null
+func (t *Type) withoutNull() *Type {
+	if !t.nullable() {
+		return t
+	}
+
+	options := []*Type{}
+	for _, option := range t.Options {
+		if !option.is(Null) {
+			options = append(options, option)
+		}
+	}
+	return unionOf(options...)
+}
+
+// widen returns the type without what is known of the contents of arrays
+// and maps
+func (t *Type) widen() *Type {
+	if t.Options != nil {
+		options := make([]*Type, len(t.Options))
+		for i, option := range t.Options {
+			options[i] = option.widen()
+		}
+		return unionOf(options...)
+	}
+	if t.Args != nil {
+		return &Type{Name: t.Name, Object: t.Object}
+	}
+	return t
+}
+
+// withContentsOf returns t with the contents of its arrays and maps as the
+// matching type of declared gives them, since the elements of an array or map
+// may later be changed to any value of the declared type
+func (t *Type) withContentsOf(declared *Type) *Type {
+	if t.Options != nil {
+		options := make([]*Type, len(t.Options))
+		for i, option := range t.Options {
+			options[i] = option.withContentsOf(declared)
+		}
+		return unionOf(options...)
+	}
+	if !t.is(Array) && !t.is(Map) {
+		return t
+	}
+
+	candidates := []*Type{declared}
+	if declared.Options != nil {
+		candidates = declared.Options
+	}
+	for _, candidate := range candidates {
+		if candidate.is(t) {
+			return &Type{Name: t.Name, Object: t.Object, Args: candidate.Args}
+		}
+	}
+	return &Type{Name: t.Name, Object: t.Object}
+}
+
+// unionOf returns the type of values of any of the given types. Unions are
+// flattened and types listed once; a union that includes an unknown type is
+// unknown.
+func unionOf(types ...*Type) *Type {
+	options := []*Type{}
+	for _, t := range types {
+		flattened := []*Type{t}
+		if t.Options != nil {
+			flattened = t.Options
+		}
+
+		for _, option := range flattened {
+			if !option.known() {
+				return Unknown
+			}
+			duplicate := false
+			for _, existing := range options {
+				duplicate = duplicate || existing.String() == option.String()
+			}
+			if !duplicate {
+				options = append(options, option)
+			}
+		}
+	}
+
+	switch len(options) {
+	case 0:
+		return Unknown
+	case 1:
+		return options[0]
+	}
+	return &Type{Options: options}
+}
+
+// numeric reports whether t is int or float
+func (t *Type) numeric() bool {
+	return t.is(Int) || t.is(Float)
+}
+
+// assignable reports whether a value of type from may be used where a value
+// of type to is expected. Unknown types are assignable both ways, and the
+// check is exact otherwise: like at runtime, an int is not a float. Arrays and
+// maps whose contents are both known must have assignable contents, functions
+// whose signatures are both known must have the same signature, and each type
+// of a union must be assignable to a type of the union expected.
+func assignable(to, from *Type) bool {
+	if from.Options != nil {
+		for _, option := range from.Options {
+			if !assignable(to, option) {
+				return false
+			}
+		}
+		return true
+	}
+	if to.Options != nil {
+		for _, option := range to.Options {
+			if assignable(option, from) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !to.known() || !from.known() {
+		return true
+	}
+	if to.Name != from.Name {
+		return false
+	}
+	if to.Args != nil && from.Args != nil {
+		for i := range to.Args {
+			if !assignable(to.Args[i], from.Args[i]) {
+				return false
+			}
+		}
+	}
+	if to.Signature == nil || from.Signature == nil {
+		return true
+	}
+
+	if len(to.Signature.Params) != len(from.Signature.Params) {
+		return false
+	}
+	for i := range to.Signature.Params {
+		if !assignable(to.Signature.Params[i], from.Signature.Params[i]) {
+			return false
+		}
+	}
+	return assignable(to.Signature.Return, from.Signature.Return)
+}
+
+// fromAnnotation returns the type named by an annotation. Names the evaluator
+// does not check are unknown.
+func fromAnnotation(annotation *ast.TypeAnnotation) *Type {
+	if annotation == nil {
+		return Unknown
+	}
+
+	if annotation.IsUnion() {
+		options := make([]*Type, len(annotation.Options))
+		for i, option := range annotation.Options {
+			options[i] = fromAnnotation(option)
+		}
+		return unionOf(options...)
+	}
+
+	if annotation.IsSignature() {
+		params := make([]*Type, len(annotation.Params))
+		for i, param := range annotation.Params {
+			params[i] = fromAnnotation(param)
+		}
+		return &Type{
+			Name:      Function.Name,
+			Object:    Function.Object,
+			Signature: &Signature{Params: params, Return: fromAnnotation(annotation.Return)},
+		}
+	}
+
+	for _, t := range []*Type{Int, Float, Bool, String, Char, Array, Map, Function, Null} {
+		if annotation.Value != t.Name {
+			continue
+		}
+		if annotation.Args == nil {
+			return t
+		}
+
+		args := make([]*Type, len(annotation.Args))
+		for i, arg := range annotation.Args {
+			args[i] = fromAnnotation(arg)
+		}
+		return &Type{Name: t.Name, Object: t.Object, Args: args}
+	}
+	return Unknown
+}
+
+// collectionOf returns the type of an array or map whose contents have the
+// given types, or the plain array or map type if any of them is unknown
+func collectionOf(t *Type, args ...*Type) *Type {
+	for _, arg := range args {
+		if !arg.known() && arg.Options == nil {
+			return t
+		}
+	}
+	return &Type{Name: t.Name, Object: t.Object, Args: args}
+}
+
+// builtin describes a builtin function: the types its first argument may have,
+// if the checker knows them, and the type of its result
+type builtin struct {
+	first  []*Type
+	result *Type
+}
+
+// builtins lists what the checker knows about the builtin functions. Builtins
+// that are not listed are called without checks and return unknown types.
+var builtins = map[string]builtin{
+	"len":    {[]*Type{String, Array, Map}, Int},
+	"push":   {[]*Type{Array}, Array},
+	"pop":    {[]*Type{Array}, Unknown},
+	"slice":  {[]*Type{Array}, Array},
+	"first":  {[]*Type{Array}, Unknown},
+	"last":   {[]*Type{Array}, Unknown},
+	"rest":   {[]*Type{Array}, Array},
+	"keys":   {[]*Type{Map}, Array},
+	"values": {[]*Type{Map}, Array},
+	"has":    {[]*Type{Map}, Bool},
+	"delete": {[]*Type{Map}, Bool},
+	"ord":    {[]*Type{Char}, Int},
+	"chr":    {[]*Type{Int}, Char},
+}